@@ -11,5 +11,6 @@ import (
 
 func main() {
 	router := routing.NewRouter()
+	routing.StartTrashPurge()
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", config.Config.Port), router))
 }
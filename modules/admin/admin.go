@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+// Audit actions recorded for admin operations.
+const (
+	ActionListUsers          = "list_users"
+	ActionViewUser           = "view_user"
+	ActionDeactivateUser     = "deactivate_user"
+	ActionReactivateUser     = "reactivate_user"
+	ActionForcePasswordReset = "force_password_reset"
+)
+
+// Usage is the amount of inventory a user keeps.
+type Usage struct {
+	LocationCount  int `json:"location_count"`
+	ContainerCount int `json:"container_count"`
+	ItemCount      int `json:"item_count"`
+}
+
+// UserSummary is a user along with their usage.
+type UserSummary struct {
+	users.User
+	Usage Usage `json:"usage"`
+}
+
+// UserFilter narrows down user listings.
+type UserFilter struct {
+	Term string
+}
+
+// UsersPagedResponse contains a group of users and meta data for pagination
+type UsersPagedResponse struct {
+	Users         []UserSummary        `json:"users"`
+	PagedResponse models.PagedResponse `json:"meta"`
+}
+
+// AuditEntry records a single admin action.
+type AuditEntry struct {
+	ID           int64     `json:"id"`
+	AdminUserID  int64     `json:"admin_user_id"`
+	Action       string    `json:"action"`
+	TargetUserID int64     `json:"target_user_id"`
+	Details      string    `json:"details"`
+	Created      time.Time `json:"created"`
+}
+
+// AuditPagedResponse contains a group of audit entries and meta data for pagination
+type AuditPagedResponse struct {
+	Entries       []AuditEntry         `json:"entries"`
+	PagedResponse models.PagedResponse `json:"meta"`
+}
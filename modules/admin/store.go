@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+// QueryLimit is the maximum number of results per page.
+const QueryLimit = 50
+
+// ErrSelfModification is returned when an admin attempts to lock themselves out.
+var ErrSelfModification = errors.New("admins can not perform this action on themselves")
+
+// Store exposes administrative queries and actions. Every action is audited.
+type Store struct {
+	DB *sql.DB
+}
+
+// NewStore constructs a storage interface for admin operations.
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func audit(db execer, adminID int64, action string, targetUserID int64, details string) error {
+	if len(details) > 255 {
+		details = details[:255]
+	}
+	q := `
+		insert into admin_audit_log (admin_user_id, action, target_user_id, details, created)
+		values (?, ?, ?, ?, now())
+	`
+	_, err := db.Exec(q, adminID, action, targetUserID, details)
+	return err
+}
+
+// GetSortBy will retrieve a SortBy object taylored for user queries
+func (s *Store) GetSortBy(field string, direction models.SortType) models.SortBy {
+	sortable := map[string]string{"id": "id", "email": "email", "created": "created", "modified": "modified"}
+	var sort models.SortBy
+	if _, ok := sortable[field]; ok {
+		sort.Field = field
+	} else {
+		sort.Field = "created"
+	}
+	if direction == models.ASC {
+		sort.Direction = models.ASC
+	} else {
+		sort.Direction = models.DSC
+	}
+	return sort
+}
+
+const userSummaryFields = `
+	u.id, u.email, u.uuid, u.is_active, u.is_verified, u.is_admin, u.reset_password, u.created, u.modified,
+	(select count(*) from locations l where l.user_id = u.id and l.deleted_at is null),
+	(select count(*) from containers c where c.user_id = u.id and c.deleted_at is null),
+	(select count(*) from container_items ci inner join containers c on c.id = ci.container_id
+		where c.user_id = u.id and c.deleted_at is null and ci.deleted_at is null)
+`
+
+func scanUserSummary(row models.Scanner) (UserSummary, error) {
+	var summary UserSummary
+	err := row.Scan(
+		&summary.ID,
+		&summary.Email,
+		&summary.UUID,
+		&summary.IsActive,
+		&summary.IsVerified,
+		&summary.IsAdmin,
+		&summary.ResetPassword,
+		&summary.Created,
+		&summary.Modified,
+		&summary.Usage.LocationCount,
+		&summary.Usage.ContainerCount,
+		&summary.Usage.ItemCount)
+	return summary, err
+}
+
+// FilteredUsers lists and searches users along with their usage.
+func (s *Store) FilteredUsers(adminID int64, filter UserFilter, sort models.SortBy, limit models.QueryLimit) (UsersPagedResponse, error) {
+	response := UsersPagedResponse{Users: []UserSummary{}}
+	if err := audit(s.DB, adminID, ActionListUsers, 0, filter.Term); err != nil {
+		return response, err
+	}
+	q := `
+		select SQL_CALC_FOUND_ROWS %v
+		from users u
+		where u.email like concat('%%', ?, '%%')
+		order by u.%v %v
+		limit %v offset %v
+	`
+	q = fmt.Sprintf(q, userSummaryFields, sort.Field, sort.Direction, limit.Limit, limit.Offset)
+	rows, err := s.DB.Query(q, filter.Term)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		summary, err := scanUserSummary(rows)
+		if err != nil {
+			return response, err
+		}
+		response.Users = append(response.Users, summary)
+	}
+	response.PagedResponse.RequestTotal = len(response.Users)
+	s.DB.QueryRow("select FOUND_ROWS()").Scan(&response.PagedResponse.Total)
+	response.PagedResponse.CalculatePages(limit)
+	return response, rows.Err()
+}
+
+// UserSummary retrieves a single user along with their usage.
+func (s *Store) UserSummary(adminID int64, userID int64) (UserSummary, error) {
+	q := fmt.Sprintf("select %v from users u where u.id = ?", userSummaryFields)
+	summary, err := scanUserSummary(s.DB.QueryRow(q, userID))
+	if err == sql.ErrNoRows {
+		return summary, users.ErrUserNotFound
+	} else if err != nil {
+		return summary, err
+	}
+	return summary, audit(s.DB, adminID, ActionViewUser, userID, "")
+}
+
+// SetActive deactivates or reactivates a user. Deactivation revokes all of the user's sessions.
+func (s *Store) SetActive(adminID int64, userID int64, active bool) error {
+	if adminID == userID && !active {
+		return ErrSelfModification
+	}
+	action := ActionReactivateUser
+	if !active {
+		action = ActionDeactivateUser
+	}
+	return s.transact(func(tx *sql.Tx) error {
+		res, err := tx.Exec("update users set is_active = ?, modified = now() where id = ?", active, userID)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			if err = s.ensureUser(tx, userID); err != nil {
+				return err
+			}
+		}
+		if !active {
+			if err = users.RevokeUserSessions(tx, userID); err != nil {
+				return err
+			}
+		}
+		return audit(tx, adminID, action, userID, "")
+	})
+}
+
+// ForcePasswordReset invalidates the user's password and sessions.
+// The user can only log in again after completing the password reset flow.
+func (s *Store) ForcePasswordReset(adminID int64, userID int64) error {
+	if adminID == userID {
+		return ErrSelfModification
+	}
+	return s.transact(func(tx *sql.Tx) error {
+		if err := s.ensureUser(tx, userID); err != nil {
+			return err
+		}
+		q := "update users set password = '', reset_password = 1, modified = now() where id = ?"
+		if _, err := tx.Exec(q, userID); err != nil {
+			return err
+		}
+		if err := users.RevokeUserSessions(tx, userID); err != nil {
+			return err
+		}
+		return audit(tx, adminID, ActionForcePasswordReset, userID, "")
+	})
+}
+
+// AuditLog retrieves audit entries, most recent first. A zero targetUserID includes all users.
+func (s *Store) AuditLog(targetUserID int64, limit models.QueryLimit) (AuditPagedResponse, error) {
+	response := AuditPagedResponse{Entries: []AuditEntry{}}
+	q := `
+		select SQL_CALC_FOUND_ROWS id, admin_user_id, action, target_user_id, details, created
+		from admin_audit_log
+		where ? = 0 or target_user_id = ?
+		order by id desc
+		limit %v offset %v
+	`
+	q = fmt.Sprintf(q, limit.Limit, limit.Offset)
+	rows, err := s.DB.Query(q, targetUserID, targetUserID)
+	if err != nil {
+		return response, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := AuditEntry{}
+		err = rows.Scan(&entry.ID, &entry.AdminUserID, &entry.Action, &entry.TargetUserID, &entry.Details, &entry.Created)
+		if err != nil {
+			return response, err
+		}
+		response.Entries = append(response.Entries, entry)
+	}
+	response.PagedResponse.RequestTotal = len(response.Entries)
+	s.DB.QueryRow("select FOUND_ROWS()").Scan(&response.PagedResponse.Total)
+	response.PagedResponse.CalculatePages(limit)
+	return response, rows.Err()
+}
+
+func (s *Store) ensureUser(tx *sql.Tx, userID int64) error {
+	var count int
+	if err := tx.QueryRow("select count(*) from users where id = ?", userID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return users.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Store) transact(fn func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
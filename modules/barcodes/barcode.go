@@ -0,0 +1,152 @@
+package barcodes
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Type is the symbology of a barcode.
+type Type string
+
+const (
+	// TypeUPCA is a 12 digit Universal Product Code.
+	TypeUPCA Type = "upc-a"
+	// TypeEAN13 is a 13 digit International Article Number.
+	TypeEAN13 Type = "ean-13"
+	// TypeCode128 holds printable ASCII text, common on shipping and asset labels.
+	TypeCode128 Type = "code128"
+	// TypeQR holds free form text such as a link.
+	TypeQR Type = "qr"
+)
+
+// MaxLength is the maximum number of characters in a barcode value.
+const MaxLength = 255
+
+var (
+	// ErrInvalidType is returned for an unknown barcode type.
+	ErrInvalidType = errors.New("barcode type must be upc-a, ean-13, code128 or qr")
+	// ErrInvalidBarcode is returned when a value can't be encoded by its barcode type.
+	ErrInvalidBarcode = errors.New("the barcode is not valid for its type")
+)
+
+// Barcode is a scanned value along with its symbology.
+type Barcode struct {
+	Type  Type   `json:"type"`
+	Value string `json:"value"`
+}
+
+// IsValid reports whether the type is known.
+func (t Type) IsValid() bool {
+	switch t {
+	case TypeUPCA, TypeEAN13, TypeCode128, TypeQR:
+		return true
+	}
+	return false
+}
+
+// New validates a barcode, returning it the way it is stored.
+// Spaces and dashes printed between the digits of UPC-A and EAN-13 codes are dropped.
+// When the type is empty it is detected from the value.
+func New(barcodeType Type, value string) (Barcode, error) {
+	value = strings.TrimSpace(value)
+	if barcodeType == "" {
+		barcodeType = Detect(value)
+	}
+	if !barcodeType.IsValid() {
+		return Barcode{}, ErrInvalidType
+	}
+	if barcodeType == TypeUPCA || barcodeType == TypeEAN13 {
+		value = strings.NewReplacer(" ", "", "-", "").Replace(value)
+	}
+	barcode := Barcode{Type: barcodeType, Value: value}
+	if value == "" || utf8.RuneCountInString(value) > MaxLength {
+		return barcode, ErrInvalidBarcode
+	}
+	switch barcodeType {
+	case TypeUPCA:
+		if len(value) != 12 || !validCheckDigit(value) {
+			return barcode, ErrInvalidBarcode
+		}
+	case TypeEAN13:
+		if len(value) != 13 || !validCheckDigit(value) {
+			return barcode, ErrInvalidBarcode
+		}
+	case TypeCode128:
+		if !printableASCII(value) {
+			return barcode, ErrInvalidBarcode
+		}
+	case TypeQR:
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return barcode, ErrInvalidBarcode
+		}
+	}
+	return barcode, nil
+}
+
+// Detect guesses the type of a barcode from its value.
+// Digits with a valid check digit are product codes, other ASCII text is Code128 and anything else a QR code.
+func Detect(value string) Type {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	switch {
+	case len(digits) == 12 && validCheckDigit(digits):
+		return TypeUPCA
+	case len(digits) == 13 && validCheckDigit(digits):
+		return TypeEAN13
+	case printableASCII(value):
+		return TypeCode128
+	}
+	return TypeQR
+}
+
+// Variants lists the values a product code is scanned as.
+// The value is normalized the way New stores it, and as a UPC-A code is the EAN-13 code with a leading zero
+// and scanners report either, both forms are included.
+func Variants(value string) []string {
+	value = normalize(value)
+	variants := []string{value}
+	if len(value) == 12 && validCheckDigit(value) {
+		variants = append(variants, "0"+value)
+	} else if len(value) == 13 && value[0] == '0' && validCheckDigit(value) {
+		variants = append(variants, value[1:])
+	}
+	return variants
+}
+
+// normalize returns a value the way New stores it when its type is detected, or trimmed when it is not valid.
+func normalize(value string) string {
+	if barcode, err := New("", value); err == nil {
+		return barcode.Value
+	}
+	return strings.TrimSpace(value)
+}
+
+// validCheckDigit verifies the GS1 check digit ending a UPC-A or EAN-13 code.
+func validCheckDigit(digits string) bool {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		// Weights alternate 1 and 3 from the right, starting with the check digit itself.
+		if (len(digits)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+func printableASCII(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
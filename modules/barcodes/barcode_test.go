@@ -0,0 +1,71 @@
+package barcodes_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/barcodes"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		barcodeType barcodes.Type
+		value       string
+		expected    barcodes.Barcode
+	}{
+		{barcodes.TypeUPCA, "036000291452", barcodes.Barcode{Type: barcodes.TypeUPCA, Value: "036000291452"}},
+		{barcodes.TypeUPCA, " 0 36000 29145 2 ", barcodes.Barcode{Type: barcodes.TypeUPCA, Value: "036000291452"}},
+		{barcodes.TypeEAN13, "4006381333931", barcodes.Barcode{Type: barcodes.TypeEAN13, Value: "4006381333931"}},
+		{barcodes.TypeCode128, "ASSET-0042", barcodes.Barcode{Type: barcodes.TypeCode128, Value: "ASSET-0042"}},
+		{barcodes.TypeQR, "https://example.com/büro", barcodes.Barcode{Type: barcodes.TypeQR, Value: "https://example.com/büro"}},
+		{"", "036000291452", barcodes.Barcode{Type: barcodes.TypeUPCA, Value: "036000291452"}},
+		{"", "4006381333931", barcodes.Barcode{Type: barcodes.TypeEAN13, Value: "4006381333931"}},
+		{"", "036000291453", barcodes.Barcode{Type: barcodes.TypeCode128, Value: "036000291453"}},
+		{"", "übergröße", barcodes.Barcode{Type: barcodes.TypeQR, Value: "übergröße"}},
+	}
+	for _, c := range cases {
+		barcode, err := barcodes.New(c.barcodeType, c.value)
+		if err != nil || barcode != c.expected {
+			t.Errorf("%q %q: expected %v but got %v, %v", c.barcodeType, c.value, c.expected, barcode, err)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	cases := []struct {
+		barcodeType barcodes.Type
+		value       string
+		expected    error
+	}{
+		{"isbn", "036000291452", barcodes.ErrInvalidType},
+		{barcodes.TypeUPCA, "036000291453", barcodes.ErrInvalidBarcode},
+		{barcodes.TypeUPCA, "4006381333931", barcodes.ErrInvalidBarcode},
+		{barcodes.TypeEAN13, "400638133393X", barcodes.ErrInvalidBarcode},
+		{barcodes.TypeCode128, "büro", barcodes.ErrInvalidBarcode},
+		{barcodes.TypeQR, "line\nbreak", barcodes.ErrInvalidBarcode},
+		{barcodes.TypeQR, strings.Repeat("x", barcodes.MaxLength+1), barcodes.ErrInvalidBarcode},
+		{barcodes.TypeCode128, " ", barcodes.ErrInvalidBarcode},
+	}
+	for _, c := range cases {
+		if _, err := barcodes.New(c.barcodeType, c.value); err != c.expected {
+			t.Errorf("%q %q: expected %v but got %v", c.barcodeType, c.value, c.expected, err)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	cases := map[string][]string{
+		"036000291452":     {"036000291452", "0036000291452"},
+		"0036000291452":    {"0036000291452", "036000291452"},
+		"4006381333931":    {"4006381333931"},
+		"ASSET-0042":       {"ASSET-0042"},
+		" 0 36000 29145 2": {"036000291452", "0036000291452"},
+		"400-6381-333931":  {"4006381333931"},
+	}
+	for value, expected := range cases {
+		if variants := barcodes.Variants(value); !reflect.DeepEqual(variants, expected) {
+			t.Errorf("%q: expected %v but got %v", value, expected, variants)
+		}
+	}
+}
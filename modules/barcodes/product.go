@@ -0,0 +1,67 @@
+package barcodes
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrProductNotFound is returned when a provider has no product for a barcode.
+var ErrProductNotFound = errors.New("no product is known for this barcode")
+
+// Product describes the goods a barcode identifies.
+type Product struct {
+	Barcode     string `json:"barcode"`
+	Name        string `json:"name"`
+	Brand       string `json:"brand,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Provider looks up product information by barcode.
+type Provider interface {
+	Lookup(value string) (Product, error)
+}
+
+// Catalog is a Provider backed by a list of products kept in memory, for offline use.
+type Catalog struct {
+	products map[string]Product
+}
+
+// NewCatalog constructs a catalog of products keyed by their barcode.
+func NewCatalog(products []Product) *Catalog {
+	catalog := &Catalog{products: make(map[string]Product)}
+	for _, product := range products {
+		catalog.products[normalize(product.Barcode)] = product
+	}
+	return catalog
+}
+
+// ReadCatalog builds a catalog from a JSON array of products.
+func ReadCatalog(r io.Reader) (*Catalog, error) {
+	var products []Product
+	if err := json.NewDecoder(r).Decode(&products); err != nil {
+		return nil, err
+	}
+	return NewCatalog(products), nil
+}
+
+// LoadCatalog builds a catalog from a JSON file.
+func LoadCatalog(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCatalog(file)
+}
+
+// Lookup finds the product of a barcode, matching UPC-A codes listed as EAN-13 and the other way around.
+func (c *Catalog) Lookup(value string) (Product, error) {
+	for _, variant := range Variants(value) {
+		if product, ok := c.products[variant]; ok {
+			return product, nil
+		}
+	}
+	return Product{}, ErrProductNotFound
+}
@@ -0,0 +1,37 @@
+package barcodes_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/barcodes"
+)
+
+func TestCatalog_Lookup(t *testing.T) {
+	catalog, err := barcodes.ReadCatalog(strings.NewReader(`[
+		{"barcode": "036000291452", "name": "Facial tissues", "brand": "Kleenex"},
+		{"barcode": "4006381-333931", "name": "Highlighter"}
+	]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, value := range []string{"036000291452", "0036000291452", "0 36000 29145 2", "0-036000-291452"} {
+		product, err := catalog.Lookup(value)
+		if err != nil || product.Name != "Facial tissues" || product.Brand != "Kleenex" {
+			t.Errorf("%q: expected the tissues but got %v, %v", value, product, err)
+		}
+	}
+	if product, err := catalog.Lookup("4006381333931"); err != nil || product.Name != "Highlighter" {
+		t.Errorf("Expected the highlighter but got %v, %v", product, err)
+	}
+	if _, err = catalog.Lookup("5901234123457"); err != barcodes.ErrProductNotFound {
+		t.Errorf("Expected ErrProductNotFound but got %v", err)
+	}
+}
+
+func TestReadCatalog_Invalid(t *testing.T) {
+	if _, err := barcodes.ReadCatalog(strings.NewReader(`{"barcode": "036000291452"}`)); err == nil {
+		t.Error("Expected an error for a catalog that is not a list of products")
+	}
+}
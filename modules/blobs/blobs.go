@@ -0,0 +1,36 @@
+package blobs
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no blob is stored under the key.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys that could escape the store, such as ones containing "..".
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps binary objects, such as photos, under slash separated keys.
+type Store interface {
+	Put(key string, contentType string, data []byte) error
+	// Get retrieves a blob. The caller must close the reader.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes a blob. Deleting a missing blob is not an error.
+	Delete(key string) error
+}
+
+// ValidKey reports if the key only contains safe path segments.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
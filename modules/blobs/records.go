@@ -0,0 +1,72 @@
+package blobs
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewUUID generates a random UUID naming a blob along with the database record describing it.
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%v-%v-%v-%v-%v", h[0:8], h[8:12], h[12:16], h[16:20], h[20:]), nil
+}
+
+// Key builds the key of a blob kept in a collection, such as "photos/{uuid}".
+func Key(collection string, name string) string {
+	return collection + "/" + name
+}
+
+// DeleteAll removes every blob, carrying on past failures and returning the first error.
+func DeleteAll(store Store, keys ...string) error {
+	var err error
+	for _, key := range keys {
+		if deleteErr := store.Delete(key); err == nil {
+			err = deleteErr
+		}
+	}
+	return err
+}
+
+// PurgeOrphans deletes the records of a table whose owner no longer exists, along with their blobs.
+// The query selects the id and uuid of every orphaned record, and keys lists the blobs stored for a uuid.
+// Owners are deleted in many ways, so records are cleaned up afterwards instead of in each of them.
+func PurgeOrphans(db *sql.DB, store Store, table string, query string, keys func(UUID string) []string, args ...interface{}) (int, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	type orphan struct {
+		ID   int64
+		UUID string
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var record orphan
+		if err = rows.Scan(&record.ID, &record.UUID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orphans = append(orphans, record)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	for i, record := range orphans {
+		if _, err = db.Exec("delete from "+table+" where id = ?", record.ID); err != nil {
+			return i, err
+		}
+		if err = DeleteAll(store, keys(record.UUID)...); err != nil {
+			return i, err
+		}
+	}
+	return len(orphans), nil
+}
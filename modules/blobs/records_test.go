@@ -0,0 +1,39 @@
+package blobs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+)
+
+func TestNewUUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := blobs.NewUUID()
+	if err != nil || !pattern.MatchString(first) {
+		t.Errorf("Expected a version 4 UUID but got %q, %v", first, err)
+	}
+	if second, _ := blobs.NewUUID(); second == first {
+		t.Error("Expected every UUID to be different")
+	}
+}
+
+func TestDeleteAll(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bmu-blobs")
+	defer os.RemoveAll(dir)
+	store := blobs.FileStore{Root: dir}
+	keys := []string{blobs.Key("photos", "one"), blobs.Key("photos", "one_thumb.jpg")}
+	for _, key := range keys {
+		store.Put(key, "image/jpeg", []byte("contents"))
+	}
+	if err := blobs.DeleteAll(store, append([]string{"../invalid"}, keys...)...); err != blobs.ErrInvalidKey {
+		t.Errorf("Expected the first error to be reported but got %v", err)
+	}
+	for _, key := range keys {
+		if _, err := store.Get(key); err != blobs.ErrNotFound {
+			t.Errorf("Expected %v to be deleted after an earlier failure but got %v", key, err)
+		}
+	}
+}
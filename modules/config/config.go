@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caarlos0/env"
 )
@@ -13,6 +14,57 @@ type Configuration struct {
 	LegacySalt string `env:"LEGACY_SALT,required"`
 	JWTSecret  string `env:"JWT_SECRET,required"`
 	WebHost    string `env:"WEB_HOST" envDefault:"http://localhost:8080"`
+	TOTPIssuer string `env:"TOTP_ISSUER" envDefault:"Boxmeup"`
+
+	// JWTSigningKey is a PEM private key (RSA or ECDSA) to sign access tokens with instead of JWTSecret.
+	// JWTVerificationKeys are previously active keys still accepted while rotating.
+	JWTSigningKey       string   `env:"JWT_SIGNING_KEY"`
+	JWTVerificationKeys []string `env:"JWT_VERIFICATION_KEYS" envSeparator:","`
+
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	VerificationTTL  time.Duration `env:"VERIFICATION_TTL" envDefault:"48h"`
+	APIRequestWindow time.Duration `env:"API_REQUEST_WINDOW" envDefault:"5m"`
+	InviteTTL        time.Duration `env:"INVITE_TTL" envDefault:"168h"`
+
+	// APIRequestMaxBytes limits the body of API key signed requests, which is read before the key is verified.
+	APIRequestMaxBytes int64 `env:"API_REQUEST_MAX_BYTES" envDefault:"1048576"`
+
+	// TrashRetention is how long deleted locations, containers and items stay in the trash.
+	TrashRetention     time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+
+	// LoginThrottleStore is where failed login counters are kept: "memory" or "database".
+	// Use "database" when running more than one server instance.
+	LoginThrottleStore string `env:"LOGIN_THROTTLE_STORE" envDefault:"memory"`
+	// TrustProxyHeaders uses X-Forwarded-For to identify clients. Only enable behind a trusted proxy.
+	TrustProxyHeaders bool `env:"TRUST_PROXY_HEADERS" envDefault:"false"`
+
+	// BlobStore is where photos are kept: "filesystem" (below BlobDir) or "s3".
+	BlobStore     string `env:"BLOB_STORE" envDefault:"filesystem"`
+	BlobDir       string `env:"BLOB_DIR" envDefault:"blobs"`
+	S3Endpoint    string `env:"S3_ENDPOINT" envDefault:"https://s3.amazonaws.com"`
+	S3Region      string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket      string `env:"S3_BUCKET"`
+	S3AccessKey   string `env:"S3_ACCESS_KEY"`
+	S3SecretKey   string `env:"S3_SECRET_KEY"`
+	PhotoMaxBytes int64  `env:"PHOTO_MAX_BYTES" envDefault:"10485760"`
+
+	// ProductCatalog is a JSON file of products used to name items created from a barcode.
+	ProductCatalog string `env:"PRODUCT_CATALOG"`
+
+	// DefaultCurrency is used for item prices saved without a currency.
+	DefaultCurrency string `env:"DEFAULT_CURRENCY" envDefault:"USD"`
+	ReceiptMaxBytes int64  `env:"RECEIPT_MAX_BYTES" envDefault:"10485760"`
+
+	MailFrom     string `env:"MAIL_FROM" envDefault:"noreply@boxmeupapp.com"`
+	MailDir      string `env:"MAIL_DIR" envDefault:"mail"`
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
 }
 
 var Config Configuration
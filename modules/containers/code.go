@@ -0,0 +1,88 @@
+package containers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CodePrefix starts every short container code.
+const CodePrefix = "BOX-"
+
+// ErrInvalidCode is returned when a short code can not be parsed.
+var ErrInvalidCode = errors.New("invalid container code")
+
+// FormatCode formats the sequence number of a container as a short code, e.g. BOX-0042.
+func FormatCode(number int64) string {
+	if number <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v%04d", CodePrefix, number)
+}
+
+// ParseCode parses a short code into its sequence number.
+// It is lenient with hand written labels: case, the dash, leading zeros and the prefix itself are optional.
+func ParseCode(code string) (int64, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.TrimPrefix(code, strings.TrimSuffix(CodePrefix, "-"))
+	code = strings.TrimPrefix(code, "-")
+	number, err := strconv.ParseInt(code, 10, 64)
+	if err != nil || number <= 0 || strings.HasPrefix(code, "+") {
+		return 0, ErrInvalidCode
+	}
+	return number, nil
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// assignCode gives the container the next short code of its household.
+// Only containers without a code are changed, so it is safe to call for existing containers.
+func assignCode(db execer, ID int64, householdID int64) (int64, error) {
+	q := "update households set container_code_seq = last_insert_id(container_code_seq + 1) where id = ?"
+	res, err := db.Exec(q, householdID)
+	if err != nil {
+		return 0, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return 0, nil
+	}
+	number, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	res, err = db.Exec("update containers set code_number = ? where id = ? and code_number is null", number, ID)
+	if err != nil {
+		return 0, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return 0, nil
+	}
+	return number, nil
+}
+
+// ByUUID retrieves a container by its UUID.
+func (c *Store) ByUUID(UUID string) (Container, error) {
+	var ID int64
+	if err := c.DB.QueryRow("select id from containers where uuid = ?", UUID).Scan(&ID); err != nil {
+		return Container{}, err
+	}
+	return c.ByID(ID)
+}
+
+// ByCode retrieves a container of a household by its short code.
+func (c *Store) ByCode(householdID int64, code string) (Container, error) {
+	number, err := ParseCode(code)
+	if err != nil {
+		return Container{}, err
+	}
+	var ID int64
+	q := "select id from containers where household_id = ? and code_number = ?"
+	if err = c.DB.QueryRow(q, householdID, number).Scan(&ID); err != nil {
+		return Container{}, err
+	}
+	return c.ByID(ID)
+}
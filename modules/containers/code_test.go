@@ -0,0 +1,30 @@
+package containers_test
+
+import (
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+)
+
+func TestFormatCode(t *testing.T) {
+	cases := map[int64]string{0: "", 42: "BOX-0042", 12345: "BOX-12345"}
+	for number, expected := range cases {
+		if code := containers.FormatCode(number); code != expected {
+			t.Errorf("Expected %q for %v but got %q", expected, number, code)
+		}
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	for _, code := range []string{"BOX-0042", "box-42", " BOX0042 ", "42"} {
+		number, err := containers.ParseCode(code)
+		if err != nil || number != 42 {
+			t.Errorf("Expected %q to parse as 42 but got %v (%v)", code, number, err)
+		}
+	}
+	for _, code := range []string{"", "BOX-", "BOX-0", "BOX--1", "BOX-+1", "CRATE-42", "BOX-42a"} {
+		if _, err := containers.ParseCode(code); err != containers.ErrInvalidCode {
+			t.Errorf("Expected %q to be invalid", code)
+		}
+	}
+}
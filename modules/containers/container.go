@@ -5,37 +5,67 @@ import (
 
 	"github.com/cjsaylor/boxmeup-go/modules/locations"
 	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
 	"github.com/cjsaylor/boxmeup-go/modules/users"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
 )
 
 // Container represents an individual container that will contain items.
+// Value totals the items directly in the container, by currency. ContainerNode.TotalValue includes nested containers.
 type Container struct {
 	ID                 int64               `json:"id"`
 	User               users.User          `json:"-"`
+	HouseholdID        int64               `json:"household_id"`
+	ParentID           int64               `json:"parent_id"`
 	Name               string              `json:"name"`
 	UUID               string              `json:"uuid"`
+	Code               string              `json:"code"`
 	Location           *locations.Location `json:"location"`
+	Tags               tags.Tags           `json:"tags"`
+	Photos             photos.Photos       `json:"photos"`
 	ContainerItemCount int                 `json:"container_item_count"`
+	Value              valuation.Totals    `json:"value"`
 	Created            time.Time           `json:"created"`
 	Modified           time.Time           `json:"modified"`
+	DeletedAt          *time.Time          `json:"deleted_at,omitempty"`
 }
 
 type ContainerRecord struct {
 	ID            int64
 	userID        int64
+	householdID   int64
 	locationID    int64
 	oldLocationID int64
+	parentID      int64
 	Name          string
 }
 
 type ContainerFilter struct {
-	User        users.User
+	User users.User
+	// HouseholdID limits results to one household. Otherwise all households of the user are included.
+	HouseholdID int64
 	LocationIDs []string
+	// WithinLocationIDs includes containers in the locations and every location nested inside them.
+	WithinLocationIDs []string
+	// AnyTags includes containers with at least one of the tag names.
+	AnyTags []string
+	// AllTags includes containers with every one of the tag names.
+	AllTags []string
 }
 
 func (f *ContainerFilter) GenericLocationIDList() []interface{} {
-	list := make([]interface{}, len(f.LocationIDs))
-	for i, val := range f.LocationIDs {
+	return genericList(f.LocationIDs)
+}
+
+// GenericWithinLocationIDList converts WithinLocationIDs to query arguments.
+func (f *ContainerFilter) GenericWithinLocationIDList() []interface{} {
+	return genericList(f.WithinLocationIDs)
+}
+
+func genericList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, val := range values {
 		list[i] = val
 	}
 	return list
@@ -68,16 +98,35 @@ func (r *ContainerRecord) SetUser(user *users.User) *ContainerRecord {
 	return r
 }
 
+// SetHousehold assigns the household the container belongs to.
+func (r *ContainerRecord) SetHousehold(householdID int64) *ContainerRecord {
+	r.householdID = householdID
+	return r
+}
+
+// SetParent nests the container inside another. A nil parent makes it a top level container.
+// Nested containers always share the location of their parent.
+func (r *ContainerRecord) SetParent(parent *Container) *ContainerRecord {
+	if parent == nil {
+		r.parentID = 0
+	} else {
+		r.parentID = parent.ID
+	}
+	return r
+}
+
 // Containers is a group of containers
 type Containers []Container
 
 func (c *Container) ToRecord() ContainerRecord {
 	record := NewRecord(&c.User)
+	record.SetHousehold(c.HouseholdID)
 	if c.ID > 0 {
 		record.ID = c.ID
 	}
 	if c.Location != nil {
 		record.SetLocation(c.Location)
 	}
+	record.parentID = c.ParentID
 	return record
 }
@@ -0,0 +1,272 @@
+package containers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+// MaxDepth is the maximum number of containers that can be nested inside each other.
+const MaxDepth = 16
+
+var (
+	// ErrContainerCycle is returned when a container would end up inside itself.
+	ErrContainerCycle = errors.New("a container can not be placed inside itself or its descendants")
+	// ErrParentHousehold is returned when the parent container belongs to another household.
+	ErrParentHousehold = errors.New("parent container belongs to another household")
+	// ErrMaxDepth is returned when nesting exceeds MaxDepth.
+	ErrMaxDepth = fmt.Errorf("containers can not be nested more than %d levels deep", MaxDepth)
+)
+
+// PathNode is a step in the path from a location to a container.
+type PathNode struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Path describes where a container is, outermost first: building > room > tote > bin.
+type Path []PathNode
+
+func (p Path) String() string {
+	names := make([]string, len(p))
+	for i, node := range p {
+		names[i] = node.Name
+	}
+	return strings.Join(names, " > ")
+}
+
+// ContainerNode is a container along with the containers nested inside it.
+type ContainerNode struct {
+	Container
+	// TotalItemCount includes the items of all nested containers.
+	TotalItemCount int `json:"total_item_count"`
+	// TotalValue includes the value of the items of all nested containers.
+	TotalValue valuation.Totals `json:"total_value"`
+	Children   []*ContainerNode `json:"children"`
+}
+
+// BuildTree arranges containers into trees. Containers whose parent is not in the list are roots.
+// Siblings are ordered by name.
+func BuildTree(list []Container) []*ContainerNode {
+	nodes := make(map[int64]*ContainerNode, len(list))
+	for _, container := range list {
+		nodes[container.ID] = &ContainerNode{Container: container, Children: []*ContainerNode{}}
+	}
+	roots := []*ContainerNode{}
+	for _, container := range list {
+		node := nodes[container.ID]
+		if parent, ok := nodes[container.ParentID]; ok && container.ParentID != container.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	for _, root := range roots {
+		sumTotals(root, 0)
+	}
+	return roots
+}
+
+func sumTotals(node *ContainerNode, depth int) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	node.TotalItemCount = node.ContainerItemCount
+	node.TotalValue = addTotals(valuation.Totals{}, node.Value)
+	if depth > MaxDepth {
+		return
+	}
+	for _, child := range node.Children {
+		sumTotals(child, depth+1)
+		node.TotalItemCount += child.TotalItemCount
+		node.TotalValue = addTotals(node.TotalValue, child.TotalValue)
+	}
+}
+
+func addTotals(totals valuation.Totals, other valuation.Totals) valuation.Totals {
+	for _, total := range other {
+		totals = totals.Add(total.Currency, total.PurchasePrice, total.ReplacementValue)
+	}
+	return totals
+}
+
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// validateParent ensures the parent is in the same household and not the container or one of its descendants,
+// and that the containers nested inside the container stay within MaxDepth once it is placed in the parent.
+// Returns the location the container inherits from the parent.
+func validateParent(db queryer, containerID int64, householdID int64, parentID int64) (int64, error) {
+	var parentHouseholdID, locationID int64
+	q := "select household_id, ifnull(location_id, 0) from containers where id = ? and deleted_at is null"
+	if err := db.QueryRow(q, parentID).Scan(&parentHouseholdID, &locationID); err != nil {
+		return 0, err
+	}
+	if parentHouseholdID != householdID {
+		return 0, ErrParentHousehold
+	}
+	// Walk up from the parent; meeting the container means it would contain itself.
+	ancestors := 0
+	for ancestorID := parentID; ancestorID > 0; {
+		if ancestorID == containerID {
+			return 0, ErrContainerCycle
+		}
+		ancestors++
+		if ancestors >= MaxDepth {
+			return 0, ErrMaxDepth
+		}
+		if err := db.QueryRow("select parent_id from containers where id = ?", ancestorID).Scan(&ancestorID); err != nil {
+			return 0, err
+		}
+	}
+	if containerID > 0 {
+		_, height, err := descendantIDs(db, containerID)
+		if err != nil {
+			return 0, err
+		}
+		if ancestors+1+height > MaxDepth {
+			return 0, ErrMaxDepth
+		}
+	}
+	return locationID, nil
+}
+
+// descendantIDs collects the IDs of every container nested inside the container,
+// along with the height of its subtree: the number of levels nested below it.
+func descendantIDs(db queryer, ID int64) ([]interface{}, int, error) {
+	var descendants []interface{}
+	height := 0
+	level := []interface{}{ID}
+	for depth := 0; len(level) > 0 && depth < MaxDepth; depth++ {
+		q := "select id from containers where deleted_at is null and parent_id in (?" + strings.Repeat(",?", len(level)-1) + ")"
+		rows, err := db.Query(q, level...)
+		if err != nil {
+			return nil, 0, err
+		}
+		level = nil
+		for rows.Next() {
+			var childID int64
+			if err = rows.Scan(&childID); err != nil {
+				rows.Close()
+				return nil, 0, err
+			}
+			level = append(level, childID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, 0, err
+		}
+		if len(level) > 0 {
+			height++
+		}
+		descendants = append(descendants, level...)
+	}
+	return descendants, height, nil
+}
+
+// Path resolves the full path of a container, starting with its location.
+func (c *Store) Path(container Container) (Path, error) {
+	path := Path{{Type: "container", ID: container.ID, Name: container.Name}}
+	parentID := container.ParentID
+	for depth := 0; parentID > 0 && depth < MaxDepth; depth++ {
+		node := PathNode{Type: "container", ID: parentID}
+		q := "select name, parent_id from containers where id = ?"
+		if err := c.DB.QueryRow(q, parentID).Scan(&node.Name, &parentID); err != nil {
+			return path, err
+		}
+		path = append(Path{node}, path...)
+	}
+	if container.Location != nil {
+		var locationPath Path
+		for _, crumb := range container.Location.Breadcrumbs {
+			locationPath = append(locationPath, PathNode{Type: "location", ID: crumb.ID, Name: crumb.Name})
+		}
+		if len(locationPath) == 0 {
+			locationPath = Path{{Type: "location", ID: container.Location.ID, Name: container.Location.Name}}
+		}
+		path = append(locationPath, path...)
+	}
+	return path, nil
+}
+
+func (c *Store) treeContainers(q string, args ...interface{}) ([]Container, error) {
+	rows, err := c.DB.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Container{}
+	for rows.Next() {
+		container := Container{}
+		var codeNumber sql.NullInt64
+		err = rows.Scan(
+			&container.ID,
+			&container.HouseholdID,
+			&container.ParentID,
+			&container.Name,
+			&container.UUID,
+			&codeNumber,
+			&container.ContainerItemCount,
+			&container.Created,
+			&container.Modified)
+		if err != nil {
+			return nil, err
+		}
+		container.Code = FormatCode(codeNumber.Int64)
+		list = append(list, container)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	IDs := make([]int64, len(list))
+	for i, container := range list {
+		IDs[i] = container.ID
+	}
+	values, err := containerValues(c.DB, IDs)
+	for i := range list {
+		list[i].Value = values[list[i].ID]
+	}
+	return list, err
+}
+
+const treeFields = "id, household_id, parent_id, name, uuid, code_number, container_item_count, created, modified"
+
+// Tree retrieves a container along with everything nested inside it.
+func (c *Store) Tree(container Container) (*ContainerNode, error) {
+	descendants, _, err := descendantIDs(c.DB, container.ID)
+	if err != nil {
+		return nil, err
+	}
+	list := []Container{container}
+	if len(descendants) > 0 {
+		q := "select " + treeFields + " from containers where id in (?" + strings.Repeat(",?", len(descendants)-1) + ")"
+		nested, err := c.treeContainers(q, descendants...)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range nested {
+			child.Location = container.Location
+			list = append(list, child)
+		}
+	}
+	// The requested container is the root even if it has a parent itself.
+	list[0].ParentID = 0
+	return BuildTree(list)[0], nil
+}
+
+// LocationTree retrieves every container at a location arranged by nesting.
+func (c *Store) LocationTree(locationID int64, householdID int64) ([]*ContainerNode, error) {
+	q := "select " + treeFields + " from containers where location_id = ? and household_id = ? and deleted_at is null"
+	list, err := c.treeContainers(q, locationID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(list), nil
+}
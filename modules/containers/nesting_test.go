@@ -0,0 +1,57 @@
+package containers_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+func TestBuildTree(t *testing.T) {
+	list := []containers.Container{
+		{ID: 1, Name: "Tote", ContainerItemCount: 2, Value: valuation.Totals{{Currency: "USD", PurchasePrice: 500}}},
+		{ID: 2, ParentID: 1, Name: "Bin B", ContainerItemCount: 3, Value: valuation.Totals{{Currency: "EUR", ReplacementValue: 800}}},
+		{ID: 3, ParentID: 1, Name: "Bin A", ContainerItemCount: 1},
+		{ID: 4, ParentID: 2, Name: "Pouch", ContainerItemCount: 4, Value: valuation.Totals{{Currency: "USD", PurchasePrice: 250, ReplacementValue: 300}}},
+		{ID: 5, ParentID: 99, Name: "Orphan"},
+	}
+	roots := containers.BuildTree(list)
+	if len(roots) != 2 {
+		t.Fatalf("Expected 2 roots, got %v", len(roots))
+	}
+	tote := roots[0]
+	if tote.ID != 1 || tote.TotalItemCount != 10 {
+		t.Errorf("Expected tote to total 10 items, got %v", tote.TotalItemCount)
+	}
+	if len(tote.Children) != 2 || tote.Children[0].Name != "Bin A" {
+		t.Error("Expected children to be ordered by name")
+	}
+	if tote.Children[1].TotalItemCount != 7 {
+		t.Errorf("Expected Bin B to total 7 items, got %v", tote.Children[1].TotalItemCount)
+	}
+	expected := valuation.Totals{
+		{Currency: "EUR", ReplacementValue: 800},
+		{Currency: "USD", PurchasePrice: 750, ReplacementValue: 300},
+	}
+	if !reflect.DeepEqual(tote.TotalValue, expected) {
+		t.Errorf("Expected tote to total %v, got %v", expected, tote.TotalValue)
+	}
+	if len(tote.Value) != 1 || tote.Value[0].PurchasePrice != 500 {
+		t.Errorf("Expected the value of the tote itself to be unchanged, got %v", tote.Value)
+	}
+	if roots[1].ID != 5 {
+		t.Error("Expected a container with an unknown parent to be a root")
+	}
+}
+
+func TestPathString(t *testing.T) {
+	path := containers.Path{
+		{Type: "location", ID: 1, Name: "Garage"},
+		{Type: "container", ID: 2, Name: "Tote"},
+		{Type: "container", ID: 3, Name: "Bin"},
+	}
+	if path.String() != "Garage > Tote > Bin" {
+		t.Errorf("Unexpected path %v", path.String())
+	}
+}
@@ -0,0 +1,170 @@
+package containers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+)
+
+// MaxRelocate is the maximum number of containers that can be relocated at once.
+const MaxRelocate = 500
+
+// Relocation statuses reported for every container.
+const (
+	RelocateMoved     = "moved"
+	RelocateUnchanged = "unchanged"
+	RelocateSkipped   = "skipped"
+)
+
+// ErrTooManyContainers is returned when relocating more than MaxRelocate containers.
+var ErrTooManyContainers = fmt.Errorf("at most %d containers can be relocated at once", MaxRelocate)
+
+// RelocateResult reports what happened to a container during a relocation.
+type RelocateResult struct {
+	ContainerID    int64  `json:"container_id"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+	FromLocationID int64  `json:"from_location_id"`
+	ToLocationID   int64  `json:"to_location_id"`
+	// NestedCount is the number of containers nested inside the container that moved along with it.
+	NestedCount int `json:"nested_count"`
+	nested      []interface{}
+}
+
+// FilteredIDs retrieves the IDs of every container matching the filter, up to MaxRelocate.
+func (c *Store) FilteredIDs(filter ContainerFilter) ([]int64, error) {
+	conditions, queryArgs, err := filter.conditions()
+	if err != nil {
+		return nil, err
+	}
+	q := fmt.Sprintf("select id from containers where %v order by id limit %v", conditions, MaxRelocate+1)
+	rows, err := c.DB.Query(q, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	IDs := []int64{}
+	for rows.Next() {
+		var ID int64
+		if err = rows.Scan(&ID); err != nil {
+			return nil, err
+		}
+		IDs = append(IDs, ID)
+	}
+	if len(IDs) > MaxRelocate {
+		return nil, ErrTooManyContainers
+	}
+	return IDs, rows.Err()
+}
+
+// Relocate moves containers to a location, or to no location when it is nil, in a single transaction.
+// Containers nested inside them move along and are left out of the results when they were selected as well.
+// Containers that can't be moved are skipped and the reason is reported; canEdit decides if the containers
+// of a household may be changed.
+// Container counts are recalculated once for every affected location.
+func (c *Store) Relocate(IDs []int64, location *locations.Location, canEdit func(householdID int64) bool) ([]RelocateResult, error) {
+	if len(IDs) > MaxRelocate {
+		return nil, ErrTooManyContainers
+	}
+	var targetID int64
+	if location != nil {
+		targetID = location.ID
+	}
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	results := []RelocateResult{}
+	affected := make(map[int64]bool)
+	seen := make(map[int64]bool)
+	for _, ID := range IDs {
+		if seen[ID] {
+			continue
+		}
+		seen[ID] = true
+		result := RelocateResult{ContainerID: ID, ToLocationID: targetID}
+		if err = relocate(tx, &result, location, canEdit); err != nil {
+			break
+		}
+		if result.Status == RelocateMoved {
+			affected[result.FromLocationID] = true
+			affected[targetID] = true
+		}
+		results = append(results, result)
+	}
+	if err == nil {
+		results = withoutNested(results)
+	}
+	for locationID := range affected {
+		if err == nil && locationID > 0 {
+			err = updateContainerCount(tx, locationID)
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// withoutNested drops the containers that were handled along with a selected parent.
+func withoutNested(results []RelocateResult) []RelocateResult {
+	handled := make(map[int64]bool)
+	for _, result := range results {
+		for _, ID := range result.nested {
+			handled[ID.(int64)] = true
+		}
+	}
+	filtered := results[:0]
+	for _, result := range results {
+		if !handled[result.ContainerID] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+func relocate(tx *sql.Tx, result *RelocateResult, location *locations.Location, canEdit func(int64) bool) error {
+	var householdID, parentID int64
+	q := "select household_id, parent_id, ifnull(location_id, 0) from containers where id = ? and deleted_at is null for update"
+	err := tx.QueryRow(q, result.ContainerID).Scan(&householdID, &parentID, &result.FromLocationID)
+	switch {
+	case err == sql.ErrNoRows || (err == nil && !canEdit(householdID)):
+		// Containers the user may not change are indistinguishable from missing ones.
+		result.Status, result.Reason = RelocateSkipped, "container not found"
+		return nil
+	case err != nil:
+		return err
+	case location != nil && location.HouseholdID != householdID:
+		result.Status, result.Reason = RelocateSkipped, "location belongs to another household"
+		return nil
+	case parentID > 0:
+		result.Status, result.Reason = RelocateSkipped, "nested containers move along with their parent"
+		return nil
+	}
+	if result.nested, _, err = descendantIDs(tx, result.ContainerID); err != nil {
+		return err
+	}
+	if result.FromLocationID == result.ToLocationID {
+		result.Status = RelocateUnchanged
+		return nil
+	}
+	q = "update containers set location_id = ?, modified = now() where id = ?"
+	if _, err = tx.Exec(q, result.ToLocationID, result.ContainerID); err != nil {
+		return err
+	}
+	if len(result.nested) > 0 {
+		q = "update containers set location_id = ?, modified = now() where id in (?" +
+			strings.Repeat(",?", len(result.nested)-1) + ")"
+		_, err = tx.Exec(q, append([]interface{}{result.ToLocationID}, result.nested...)...)
+	}
+	result.NestedCount = len(result.nested)
+	result.Status = RelocateMoved
+	return err
+}
@@ -10,7 +10,10 @@ import (
 
 	"github.com/cjsaylor/boxmeup-go/modules/locations"
 	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
 	"github.com/cjsaylor/boxmeup-go/modules/users"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
 )
 
 // QueryLimit is the maximum number of container results per page.
@@ -44,31 +47,46 @@ func (c *Store) GetSortBy(field string, direction models.SortType) models.SortBy
 	return sort
 }
 
-// Create persists a container to the database
+// Create persists a container to the database.
+// A nested container takes the location of its parent.
 func (c *Store) Create(record *ContainerRecord) error {
 	if record.Name == "" {
 		return errors.New("Container must have a name")
 	}
 	q := `
-		insert into containers (user_id, location_id, name, uuid, created, modified)
-		values (?, ?, ?, uuid(), now(), now())
+		insert into containers (user_id, household_id, parent_id, location_id, name, uuid, created, modified)
+		values (?, ?, ?, ?, ?, uuid(), now(), now())
 	`
-	tx, _ := c.DB.Begin()
-	res, err := tx.Exec(q, record.userID, record.locationID, record.Name)
-	if err == nil && record.locationID > 0 {
-		err = updateContainerCount(tx, record.locationID)
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if record.parentID > 0 {
+		record.locationID, err = validateParent(tx, 0, record.householdID, record.parentID)
+	}
+	var res sql.Result
+	if err == nil {
+		res, err = tx.Exec(q, record.userID, record.householdID, record.parentID, record.locationID, record.Name)
+	}
+	if err == nil {
+		record.ID, _ = res.LastInsertId()
+		_, err = assignCode(tx, record.ID, record.householdID)
+	}
+	if err == nil {
+		if record.locationID > 0 {
+			err = updateContainerCount(tx, record.locationID)
+		}
 	}
 	if err == nil {
-		tx.Commit()
+		err = tx.Commit()
 	} else {
 		tx.Rollback()
 	}
-	record.ID, _ = res.LastInsertId()
-
 	return err
 }
 
-// Update a container
+// Update a container.
+// Containers nested inside it, at any depth, move along to its new location.
 func (c *Store) Update(record *ContainerRecord) error {
 	if record.ID == 0 {
 		return errors.New("can not update a container without it first being persisted")
@@ -76,41 +94,69 @@ func (c *Store) Update(record *ContainerRecord) error {
 	if record.Name == "" {
 		return errors.New("containers must have a name")
 	}
-	q := `
-		update containers set name = ?, location_id = ?, modified = now()
-		where id = ?
-	`
-	tx, _ := c.DB.Begin()
-	_, err := tx.Exec(q, record.Name, record.locationID, record.ID)
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var oldLocationID int64
+	q := "select ifnull(location_id, 0) from containers where id = ? and deleted_at is null for update"
+	err = tx.QueryRow(q, record.ID).Scan(&oldLocationID)
+	if err == nil && record.parentID > 0 {
+		record.locationID, err = validateParent(tx, record.ID, record.householdID, record.parentID)
+	}
 	if err == nil {
-		if record.locationID > 0 {
+		q = `
+			update containers set name = ?, parent_id = ?, location_id = ?, modified = now()
+			where id = ?
+		`
+		_, err = tx.Exec(q, record.Name, record.parentID, record.locationID, record.ID)
+	}
+	if err == nil && oldLocationID != record.locationID {
+		var descendants []interface{}
+		descendants, _, err = descendantIDs(tx, record.ID)
+		if err == nil && len(descendants) > 0 {
+			q = "update containers set location_id = ?, modified = now() where id in (?" +
+				strings.Repeat(",?", len(descendants)-1) + ")"
+			_, err = tx.Exec(q, append([]interface{}{record.locationID}, descendants...)...)
+		}
+		if err == nil && record.locationID > 0 {
 			err = updateContainerCount(tx, record.locationID)
 		}
-		if err == nil && record.oldLocationID > 0 {
-			err = updateContainerCount(tx, record.oldLocationID)
+		if err == nil && oldLocationID > 0 {
+			err = updateContainerCount(tx, oldLocationID)
 		}
 	}
 	if err == nil {
-		tx.Commit()
+		err = tx.Commit()
 	} else {
 		tx.Rollback()
 	}
 	return err
 }
 
-// Delete will remove a container by its ID.
-// Note that due to the FK constrant set to cascade on deletion, this will
-// delete all the related items as well.
-func (c *Store) Delete(ID int64) error {
-	// Note, the FK has cascade deletion, so this will delete the items as well.
-	q := "delete from containers where id = ?"
-	tx, _ := c.DB.Begin()
-	_, err := tx.Exec(q, ID)
+// Delete moves a container along with its items to the trash of the user,
+// and updates the container count of its location.
+// Containers nested directly inside it are moved up to its parent.
+func (c *Store) Delete(ID int64, userID int64) error {
+	tx, err := c.DB.Begin()
 	if err != nil {
-		err = updateContainerCount(tx, ID)
+		return err
 	}
+	var locationID, parentID int64
+	q := "select ifnull(location_id, 0), parent_id from containers where id = ? and deleted_at is null for update"
+	err = tx.QueryRow(q, ID).Scan(&locationID, &parentID)
 	if err == nil {
-		tx.Commit()
+		q = "update containers set parent_id = ?, modified = now() where parent_id = ? and deleted_at is null"
+		_, err = tx.Exec(q, parentID, ID)
+	}
+	if err == nil {
+		_, err = tx.Exec("update containers set deleted_at = now(), deleted_by = ? where id = ?", userID, ID)
+	}
+	if err == nil && locationID > 0 {
+		err = updateContainerCount(tx, locationID)
+	}
+	if err == nil {
+		err = tx.Commit()
 	} else {
 		tx.Rollback()
 	}
@@ -122,7 +168,7 @@ func updateContainerCount(tx *sql.Tx, locationID int64) error {
 	q := `
 		update locations
 		set container_count = (
-			select count(*) from containers where location_id = ?
+			select count(*) from containers where location_id = ? and deleted_at is null
 		), modified = now()
 		where id = ?
 	`
@@ -130,30 +176,48 @@ func updateContainerCount(tx *sql.Tx, locationID int64) error {
 	return err
 }
 
+// containerValues totals the value of the items directly in each of the containers.
+func containerValues(db *sql.DB, IDs []int64) (map[int64]valuation.Totals, error) {
+	if len(IDs) == 0 {
+		return map[int64]valuation.Totals{}, nil
+	}
+	args := make([]interface{}, len(IDs))
+	for i, ID := range IDs {
+		args[i] = ID
+	}
+	source := "container_items ci where ci.container_id in (?" + strings.Repeat(",?", len(IDs)-1) + ") and ci.deleted_at is null"
+	return valuation.Rollup(db, "ci.container_id", source, IDs, args...)
+}
+
 // ByID retrieves a container by its primary ID
 func (c *Store) ByID(ID int64) (Container, error) {
 	var userID int64
 	var locationID int64
 	q := `
-		select id, user_id, location_id, name, uuid, container_item_count, created, modified
+		select id, user_id, household_id, parent_id, location_id, name, uuid, code_number, container_item_count, created, modified
 		from containers
-		where id = ?
+		where id = ? and deleted_at is null
 	`
 	var container Container
+	var codeNumber sql.NullInt64
 	err := c.DB.QueryRow(q, ID).Scan(
 		&container.ID,
 		&userID,
+		&container.HouseholdID,
+		&container.ParentID,
 		&locationID,
 		&container.Name,
 		&container.UUID,
+		&codeNumber,
 		&container.ContainerItemCount,
 		&container.Created,
 		&container.Modified)
 	if err != nil {
 		return container, err
 	}
+	container.Code = FormatCode(codeNumber.Int64)
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(5)
 	go func(userID int64, container *Container) {
 		defer wg.Done()
 		container.User, err = users.NewStore(c.DB).ByID(userID)
@@ -165,6 +229,21 @@ func (c *Store) ByID(ID int64) (Container, error) {
 			container.Location = &location
 		}
 	}(locationID, &container)
+	go func(container *Container) {
+		defer wg.Done()
+		tagged, _ := tags.NewStore(c.DB).ContainerTags([]int64{container.ID})
+		container.Tags = tagged[container.ID]
+	}(&container)
+	go func(container *Container) {
+		defer wg.Done()
+		owned, _ := photos.NewStore(c.DB, nil).ForOwners(photos.OwnerContainer, []int64{container.ID})
+		container.Photos = owned[container.ID]
+	}(&container)
+	go func(container *Container) {
+		defer wg.Done()
+		values, _ := containerValues(c.DB, []int64{container.ID})
+		container.Value = values[container.ID]
+	}(&container)
 
 	wg.Wait()
 
@@ -179,22 +258,65 @@ func (r *PagedResponse) getContainerIDMap() map[int64]*Container {
 	return mappedContainers
 }
 
+// conditions builds the where clause of the filter along with its arguments.
+// tags.ErrInvalidName is returned when a tag name of the filter is invalid.
+func (f *ContainerFilter) conditions() (string, []interface{}, error) {
+	q := "deleted_at is null and household_id in (select household_id from household_members where user_id = ?)"
+	queryArgs := []interface{}{f.User.ID}
+	if f.HouseholdID > 0 {
+		q += " and household_id = ?"
+		queryArgs = append(queryArgs, f.HouseholdID)
+	}
+	if len(f.LocationIDs) > 0 {
+		q += " and location_id in (?" + strings.Repeat(",?", len(f.LocationIDs)-1) + ")"
+		queryArgs = append(queryArgs, f.GenericLocationIDList()...)
+	}
+	if len(f.WithinLocationIDs) > 0 {
+		q += " and " + locations.SubtreeCondition("location_id", len(f.WithinLocationIDs))
+		queryArgs = append(queryArgs, f.GenericWithinLocationIDList()...)
+	}
+	anyTags, err := tags.NormalizeNames(f.AnyTags)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(anyTags) > 0 {
+		q += ` and id in (
+			select ct.container_id from container_tags ct inner join tags t on t.id = ct.tag_id
+			where t.name in (?` + strings.Repeat(",?", len(anyTags)-1) + `)
+		)`
+		queryArgs = append(queryArgs, genericList(anyTags)...)
+	}
+	allTags, err := tags.NormalizeNames(f.AllTags)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(allTags) > 0 {
+		q += ` and id in (
+			select ct.container_id from container_tags ct inner join tags t on t.id = ct.tag_id
+			where t.name in (?` + strings.Repeat(",?", len(allTags)-1) + `)
+			group by ct.container_id
+			having count(*) = ?
+		)`
+		queryArgs = append(append(queryArgs, genericList(allTags)...), len(allTags))
+	}
+	return q, queryArgs, nil
+}
+
 // FilteredContainers will retrieve paginated list of containers with provided filter params.
+// Only containers in households the filter user is a member of are included.
 func (c *Store) FilteredContainers(filter ContainerFilter, sort models.SortBy, limit models.QueryLimit) (PagedResponse, error) {
 	q := `
-		select SQL_CALC_FOUND_ROWS id, location_id, name, uuid, container_item_count, created, modified
+		select SQL_CALC_FOUND_ROWS id, household_id, parent_id, location_id, name, uuid, code_number, container_item_count, created, modified
 		from containers
-		where user_id = ? %v
+		where %v
 		order by %v %v
 		limit %v offset %v
 	`
-	locationIDQueryModifier := ""
-	queryArgs := []interface{}{filter.User.ID}
-	if len(filter.LocationIDs) > 0 {
-		locationIDQueryModifier = "and location_id in (?" + strings.Repeat(",?", len(filter.LocationIDs)-1) + ")"
-		queryArgs = append(queryArgs, filter.GenericLocationIDList()...)
+	conditions, queryArgs, err := filter.conditions()
+	if err != nil {
+		return PagedResponse{}, err
 	}
-	q = fmt.Sprintf(q, locationIDQueryModifier, sort.Field, sort.Direction, limit.Limit, limit.Offset)
+	q = fmt.Sprintf(q, conditions, sort.Field, sort.Direction, limit.Limit, limit.Offset)
 	rows, err := c.DB.Query(q, queryArgs...)
 	if err != nil {
 		log.Fatal(err)
@@ -203,16 +325,21 @@ func (c *Store) FilteredContainers(filter ContainerFilter, sort models.SortBy, l
 	defer rows.Close()
 	locationIDs := make(map[int64]int64)
 	var locationID int64
+	var codeNumber sql.NullInt64
 	for rows.Next() {
 		container := Container{}
 		rows.Scan(
 			&container.ID,
+			&container.HouseholdID,
+			&container.ParentID,
 			&locationID,
 			&container.Name,
 			&container.UUID,
+			&codeNumber,
 			&container.ContainerItemCount,
 			&container.Created,
 			&container.Modified)
+		container.Code = FormatCode(codeNumber.Int64)
 		if locationID > 0 {
 			locationIDs[container.ID] = locationID
 		}
@@ -235,7 +362,28 @@ func (c *Store) FilteredContainers(filter ContainerFilter, sort models.SortBy, l
 			}
 		}(v, containerMap[k])
 	}
+	containerIDs := make([]int64, len(response.Containers))
+	for i, container := range response.Containers {
+		containerIDs[i] = container.ID
+	}
+	tagged, err := tags.NewStore(c.DB).ContainerTags(containerIDs)
+	owned, photoErr := photos.NewStore(c.DB, nil).ForOwners(photos.OwnerContainer, containerIDs)
+	if err == nil {
+		err = photoErr
+	}
+	values, valueErr := containerValues(c.DB, containerIDs)
+	if err == nil {
+		err = valueErr
+	}
+	for i := range response.Containers {
+		response.Containers[i].Tags = tagged[response.Containers[i].ID]
+		response.Containers[i].Photos = owned[response.Containers[i].ID]
+		response.Containers[i].Value = values[response.Containers[i].ID]
+	}
 	response.PagedResponse.CalculatePages(limit)
 	wg.Wait()
+	if err != nil {
+		return response, err
+	}
 	return response, rows.Err()
 }
@@ -0,0 +1,92 @@
+package containers_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	"github.com/cjsaylor/sqlfixture"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	// @todo replace this with configured database from app
+	db, _ = sql.Open("mysql", "root:supersecret@tcp(localhost:3306)/bmu_test?parseTime=true")
+	defer db.Close()
+	os.Exit(m.Run())
+}
+
+func setup(db *sql.DB) {
+	db.Exec("SET FOREIGN_KEY_CHECKS=0")
+	fixture := sqlfixture.New(db, sqlfixture.Tables{
+		sqlfixture.Table{
+			Name: "users",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":        1,
+					"email":     "test@test.com",
+					"is_active": 1,
+					"created":   "2017-05-15",
+					"modified":  "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "households",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":               1,
+					"uuid":             "0b9d7a57-4183-11e7-9cc8-0242ac120003",
+					"name":             "Personal",
+					"personal_user_id": 1,
+					"created":          "2017-05-15",
+					"modified":         "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "containers",
+		},
+	})
+	fixture.Populate()
+	db.Exec("SET FOREIGN_KEY_CHECKS=1")
+}
+
+func createContainer(t *testing.T, name string, parent *containers.Container) *containers.Container {
+	user := users.User{ID: 1}
+	record := containers.NewRecord(&user)
+	record.SetHousehold(1).SetParent(parent)
+	record.Name = name
+	if err := containers.NewStore(db).Create(&record); err != nil {
+		t.Fatal(err)
+	}
+	return &containers.Container{ID: record.ID, HouseholdID: 1, Name: name}
+}
+
+func TestStore_UpdateMaxDepth(t *testing.T) {
+	setup(db)
+	containerModel := containers.NewStore(db)
+	var deepest *containers.Container
+	for level := 1; level < containers.MaxDepth; level++ {
+		deepest = createContainer(t, fmt.Sprintf("Level %d", level), deepest)
+	}
+	box := createContainer(t, "Box", nil)
+	bin := createContainer(t, "Bin", box)
+	record := box.ToRecord()
+	record.Name = box.Name
+	record.SetParent(deepest)
+	if err := containerModel.Update(&record); err != containers.ErrMaxDepth {
+		t.Errorf("Expected moving a 2 level subtree under a %d level chain to fail but got %v", containers.MaxDepth-1, err)
+	}
+	record = bin.ToRecord()
+	record.Name = bin.Name
+	record.SetParent(deepest)
+	if err := containerModel.Update(&record); err != nil {
+		t.Errorf("Expected a single container to fit at the deepest level but got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package containers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+const trashFields = "id, household_id, parent_id, name, uuid, code_number, container_item_count, created, modified, deleted_at"
+
+func scanTrashed(row models.Scanner) (Container, error) {
+	var container Container
+	var codeNumber sql.NullInt64
+	var deletedAt time.Time
+	err := row.Scan(
+		&container.ID,
+		&container.HouseholdID,
+		&container.ParentID,
+		&container.Name,
+		&container.UUID,
+		&codeNumber,
+		&container.ContainerItemCount,
+		&container.Created,
+		&container.Modified,
+		&deletedAt)
+	container.Code = FormatCode(codeNumber.Int64)
+	container.DeletedAt = &deletedAt
+	return container, err
+}
+
+// Trashed lists the containers in the trash of a user, most recently deleted first.
+// Containers of households the user has since left are not included.
+func (c *Store) Trashed(userID int64) ([]Container, error) {
+	q := `
+		select ` + trashFields + `
+		from containers
+		where deleted_by = ? and deleted_at is not null
+			and household_id in (select household_id from household_members where user_id = ?)
+		order by deleted_at desc
+	`
+	rows, err := c.DB.Query(q, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Container{}
+	for rows.Next() {
+		container, err := scanTrashed(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, container)
+	}
+	return list, rows.Err()
+}
+
+// TrashedByID retrieves a container from the trash of a user.
+func (c *Store) TrashedByID(ID int64, userID int64) (Container, error) {
+	q := "select " + trashFields + " from containers where id = ? and deleted_by = ? and deleted_at is not null"
+	return scanTrashed(c.DB.QueryRow(q, ID, userID))
+}
+
+// Restore takes a container out of the trash along with its items.
+// It goes back into its parent, or to its location when the parent is gone,
+// and is left without a location when that is gone as well.
+func (c *Store) Restore(container Container) error {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var parentID, locationID int64
+	q := "select parent_id, ifnull(location_id, 0) from containers where id = ? and deleted_at is not null for update"
+	err = tx.QueryRow(q, container.ID).Scan(&parentID, &locationID)
+	if err == nil && parentID > 0 {
+		var parentLocationID int64
+		parentLocationID, err = validateParent(tx, container.ID, container.HouseholdID, parentID)
+		if err == nil {
+			locationID = parentLocationID
+		} else {
+			parentID, err = 0, nil
+		}
+	}
+	if err == nil && locationID > 0 {
+		var live int
+		q = "select count(*) from locations where id = ? and household_id = ? and deleted_at is null"
+		err = tx.QueryRow(q, locationID, container.HouseholdID).Scan(&live)
+		if live == 0 {
+			locationID = 0
+		}
+	}
+	if err == nil {
+		q = `
+			update containers set parent_id = ?, location_id = ?, deleted_at = null, deleted_by = null, modified = now()
+			where id = ?
+		`
+		_, err = tx.Exec(q, parentID, locationID, container.ID)
+	}
+	if err == nil && locationID > 0 {
+		err = updateContainerCount(tx, locationID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
+
+// PurgeTrash permanently deletes the containers, and their items, that were trashed before the given time.
+func (c *Store) PurgeTrash(before time.Time) (int64, error) {
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	q := `
+		delete ci from container_items ci
+		inner join containers c on c.id = ci.container_id
+		where c.deleted_at < ?
+	`
+	var purged int64
+	_, err = tx.Exec(q, before)
+	if err == nil {
+		var res sql.Result
+		res, err = tx.Exec("delete from containers where deleted_at < ?", before)
+		if err == nil {
+			purged, _ = res.RowsAffected()
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return purged, err
+}
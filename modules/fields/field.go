@@ -0,0 +1,185 @@
+package fields
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Type is the kind of value a field holds.
+type Type string
+
+const (
+	// TypeText holds free form text, e.g. a model name.
+	TypeText Type = "text"
+	// TypeNumber holds a decimal number.
+	TypeNumber Type = "number"
+	// TypeDate holds a calendar date formatted as DateFormat.
+	TypeDate Type = "date"
+	// TypeBoolean holds true or false.
+	TypeBoolean Type = "boolean"
+	// TypeEnum holds one of the options of the field.
+	TypeEnum Type = "enum"
+	// TypeURL holds an http or https link.
+	TypeURL Type = "url"
+)
+
+const (
+	// MaxNameLength is the maximum number of characters in a field name or enum option.
+	MaxNameLength = 40
+	// MaxValueLength is the maximum number of characters in a field value.
+	MaxValueLength = 255
+	// DateFormat is the format of date values.
+	DateFormat = "2006-01-02"
+)
+
+var (
+	// ErrInvalidName is returned for empty or overly long field names.
+	ErrInvalidName = errors.New("field names must be between 1 and 40 characters")
+	// ErrInvalidType is returned for an unknown field type.
+	ErrInvalidType = errors.New("field type must be text, number, date, boolean, enum or url")
+	// ErrInvalidOptions is returned when an enum field has no options, or another type of field has some.
+	ErrInvalidOptions = errors.New("enum fields need options of at most 40 characters and other fields take none")
+)
+
+// ValueError is returned when a value does not fit its field.
+type ValueError struct {
+	Field  string
+	Reason string
+}
+
+func (e ValueError) Error() string {
+	return fmt.Sprintf("%v %v", e.Field, e.Reason)
+}
+
+// Definition describes a custom field the items of a household can have, e.g. a serial number or color.
+type Definition struct {
+	ID          int64     `json:"id"`
+	HouseholdID int64     `json:"household_id"`
+	Name        string    `json:"name"`
+	Type        Type      `json:"type"`
+	Options     []string  `json:"options,omitempty"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// IsValid reports whether the type is known.
+func (t Type) IsValid() bool {
+	switch t {
+	case TypeText, TypeNumber, TypeDate, TypeBoolean, TypeEnum, TypeURL:
+		return true
+	}
+	return false
+}
+
+// NormalizeName collapses the whitespace of a field name.
+func NormalizeName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// NormalizeOptions validates the options of a field, removing blanks and duplicates.
+func NormalizeOptions(fieldType Type, options []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, option := range options {
+		option = strings.Join(strings.Fields(option), " ")
+		if option == "" || seen[strings.ToLower(option)] {
+			continue
+		}
+		if utf8.RuneCountInString(option) > MaxNameLength {
+			return nil, ErrInvalidOptions
+		}
+		seen[strings.ToLower(option)] = true
+		normalized = append(normalized, option)
+	}
+	if (fieldType == TypeEnum) != (len(normalized) > 0) {
+		return nil, ErrInvalidOptions
+	}
+	return normalized, nil
+}
+
+// Normalize validates a value for the field, returning it the way it is stored.
+// Numbers lose insignificant zeros, booleans become true or false and enum values take the case of their option.
+func (d Definition) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) > MaxValueLength {
+		return "", ValueError{d.Name, "is too long"}
+	}
+	switch d.Type {
+	case TypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return "", ValueError{d.Name, "must be a number"}
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case TypeDate:
+		date, err := time.Parse(DateFormat, value)
+		if err != nil {
+			return "", ValueError{d.Name, "must be a date formatted as YYYY-MM-DD"}
+		}
+		return date.Format(DateFormat), nil
+	case TypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", ValueError{d.Name, "must be true or false"}
+		}
+		return strconv.FormatBool(boolean), nil
+	case TypeEnum:
+		for _, option := range d.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return "", ValueError{d.Name, "must be one of " + strings.Join(d.Options, ", ")}
+	case TypeURL:
+		link, err := url.Parse(value)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			return "", ValueError{d.Name, "must be an http or https link"}
+		}
+		return link.String(), nil
+	}
+	return value, nil
+}
+
+// Value is the value of a custom field on an item.
+// An empty value removes the field from the item.
+type Value struct {
+	FieldID int64
+	Name    string
+	Type    Type
+	Value   string
+}
+
+// Values are the custom fields of an item.
+type Values []Value
+
+// MarshalJSON outputs numbers and booleans as JSON numbers and booleans.
+func (v Value) MarshalJSON() ([]byte, error) {
+	var typed interface{} = v.Value
+	switch v.Type {
+	case TypeNumber:
+		if number, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			typed = number
+		}
+	case TypeBoolean:
+		if boolean, err := strconv.ParseBool(v.Value); err == nil {
+			typed = boolean
+		}
+	}
+	return json.Marshal(struct {
+		FieldID int64       `json:"field_id"`
+		Name    string      `json:"name"`
+		Type    Type        `json:"type"`
+		Value   interface{} `json:"value"`
+	}{v.FieldID, v.Name, v.Type, typed})
+}
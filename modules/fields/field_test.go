@@ -0,0 +1,103 @@
+package fields_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+)
+
+func TestDefinition_Normalize(t *testing.T) {
+	cases := []struct {
+		definition fields.Definition
+		value      string
+		expected   string
+	}{
+		{fields.Definition{Type: fields.TypeText}, "  SN-1234 ", "SN-1234"},
+		{fields.Definition{Type: fields.TypeNumber}, "12.50", "12.5"},
+		{fields.Definition{Type: fields.TypeNumber}, "-3", "-3"},
+		{fields.Definition{Type: fields.TypeDate}, "2017-05-15", "2017-05-15"},
+		{fields.Definition{Type: fields.TypeBoolean}, "1", "true"},
+		{fields.Definition{Type: fields.TypeBoolean}, "FALSE", "false"},
+		{fields.Definition{Type: fields.TypeEnum, Options: []string{"Red", "Blue"}}, "blue", "Blue"},
+		{fields.Definition{Type: fields.TypeURL}, "https://example.com/manual.pdf", "https://example.com/manual.pdf"},
+	}
+	for _, c := range cases {
+		normalized, err := c.definition.Normalize(c.value)
+		if err != nil || normalized != c.expected {
+			t.Errorf("%v %q: expected %q but got %q, %v", c.definition.Type, c.value, c.expected, normalized, err)
+		}
+	}
+}
+
+func TestDefinition_NormalizeInvalid(t *testing.T) {
+	cases := []struct {
+		definition fields.Definition
+		value      string
+	}{
+		{fields.Definition{Type: fields.TypeNumber}, "twelve"},
+		{fields.Definition{Type: fields.TypeNumber}, "NaN"},
+		{fields.Definition{Type: fields.TypeDate}, "15/05/2017"},
+		{fields.Definition{Type: fields.TypeBoolean}, "maybe"},
+		{fields.Definition{Type: fields.TypeEnum, Options: []string{"Red", "Blue"}}, "Green"},
+		{fields.Definition{Type: fields.TypeURL}, "javascript:alert(1)"},
+		{fields.Definition{Type: fields.TypeURL}, "example.com"},
+	}
+	for _, c := range cases {
+		if _, err := c.definition.Normalize(c.value); err == nil {
+			t.Errorf("%v %q: expected a validation error", c.definition.Type, c.value)
+		} else if _, ok := err.(fields.ValueError); !ok {
+			t.Errorf("%v %q: expected a ValueError but got %v", c.definition.Type, c.value, err)
+		}
+	}
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	options, err := fields.NormalizeOptions(fields.TypeEnum, []string{" Red ", "red", "", "Dark  Blue"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expected := []string{"Red", "Dark Blue"}; !reflect.DeepEqual(options, expected) {
+		t.Errorf("Expected %v but got %v", expected, options)
+	}
+	if _, err = fields.NormalizeOptions(fields.TypeEnum, []string{" "}); err != fields.ErrInvalidOptions {
+		t.Errorf("Expected enum fields to need options but got %v", err)
+	}
+	if _, err = fields.NormalizeOptions(fields.TypeText, []string{"Red"}); err != fields.ErrInvalidOptions {
+		t.Errorf("Expected text fields to refuse options but got %v", err)
+	}
+}
+
+func TestValue_MarshalJSON(t *testing.T) {
+	values := fields.Values{
+		{FieldID: 1, Name: "Watts", Type: fields.TypeNumber, Value: "60"},
+		{FieldID: 2, Name: "Working", Type: fields.TypeBoolean, Value: "true"},
+		{FieldID: 3, Name: "Color", Type: fields.TypeEnum, Value: "Red"},
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := `[{"field_id":1,"name":"Watts","type":"number","value":60},` +
+		`{"field_id":2,"name":"Working","type":"boolean","value":true},` +
+		`{"field_id":3,"name":"Color","type":"enum","value":"Red"}]`
+	if string(encoded) != expected {
+		t.Errorf("Expected %v but got %v", expected, string(encoded))
+	}
+}
+
+func TestValues_Condition(t *testing.T) {
+	values := fields.Values{{FieldID: 1, Value: "Red"}, {FieldID: 4, Value: "12"}}
+	condition, args := values.Condition("ci.id")
+	expected := "ci.id in (select item_id from item_field_values where field_id = ? and value = ?)" +
+		" and ci.id in (select item_id from item_field_values where field_id = ? and value = ?)"
+	if condition != expected {
+		t.Errorf("Unexpected condition %v", condition)
+	}
+	if expectedArgs := []interface{}{int64(1), "Red", int64(4), "12"}; !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected %v but got %v", expectedArgs, args)
+	}
+}
@@ -0,0 +1,267 @@
+package fields
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+var (
+	// ErrFieldExists is returned when a household already has a field with the name.
+	ErrFieldExists = errors.New("a field with this name already exists")
+	// ErrFieldNotFound is returned when a field does not exist in the household.
+	ErrFieldNotFound = errors.New("field not found")
+)
+
+// Store persists the custom field definitions of households and the field values of items.
+type Store struct {
+	DB *sql.DB
+}
+
+// NewStore constructs a storage interface for custom fields.
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+func in(count int) string {
+	return "(?" + strings.Repeat(",?", count-1) + ")"
+}
+
+// Create adds a field definition to a household.
+func (s *Store) Create(householdID int64, name string, fieldType Type, options []string) (Definition, error) {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return Definition{}, err
+	}
+	if !fieldType.IsValid() {
+		return Definition{}, ErrInvalidType
+	}
+	if options, err = NormalizeOptions(fieldType, options); err != nil {
+		return Definition{}, err
+	}
+	encoded, _ := json.Marshal(options)
+	q := "insert into fields (household_id, name, type, options, created, modified) values (?, ?, ?, ?, now(), now())"
+	res, err := s.DB.Exec(q, householdID, name, fieldType, encoded)
+	if models.IsDuplicate(err) {
+		return Definition{}, ErrFieldExists
+	} else if err != nil {
+		return Definition{}, err
+	}
+	ID, _ := res.LastInsertId()
+	return s.ByID(ID)
+}
+
+const definitionFields = "id, household_id, name, type, options, created, modified"
+
+func scanDefinition(row models.Scanner) (Definition, error) {
+	var definition Definition
+	var options []byte
+	err := row.Scan(
+		&definition.ID,
+		&definition.HouseholdID,
+		&definition.Name,
+		&definition.Type,
+		&options,
+		&definition.Created,
+		&definition.Modified)
+	if err == nil && len(options) > 0 {
+		err = json.Unmarshal(options, &definition.Options)
+	}
+	return definition, err
+}
+
+// ByID retrieves a field definition.
+func (s *Store) ByID(ID int64) (Definition, error) {
+	definition, err := scanDefinition(s.DB.QueryRow("select "+definitionFields+" from fields where id = ?", ID))
+	if err == sql.ErrNoRows {
+		err = ErrFieldNotFound
+	}
+	return definition, err
+}
+
+// Definitions lists the field definitions of a household by name.
+func (s *Store) Definitions(householdID int64) ([]Definition, error) {
+	rows, err := s.DB.Query("select "+definitionFields+" from fields where household_id = ? order by name", householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	definitions := []Definition{}
+	for rows.Next() {
+		definition, err := scanDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, rows.Err()
+}
+
+// Update renames a field and replaces the options of an enum field.
+// The type of a field can't change. Items keep values that are no longer an option until they are edited.
+func (s *Store) Update(definition Definition) error {
+	name, err := NormalizeName(definition.Name)
+	if err != nil {
+		return err
+	}
+	options, err := NormalizeOptions(definition.Type, definition.Options)
+	if err != nil {
+		return err
+	}
+	encoded, _ := json.Marshal(options)
+	q := "update fields set name = ?, options = ?, modified = now() where id = ?"
+	_, err = s.DB.Exec(q, name, encoded, definition.ID)
+	if models.IsDuplicate(err) {
+		return ErrFieldExists
+	}
+	return err
+}
+
+// Delete removes a field definition along with its value on every item.
+func (s *Store) Delete(ID int64) error {
+	_, err := s.DB.Exec("delete from fields where id = ?", ID)
+	return err
+}
+
+// Validate checks values, keyed by field ID, against the field definitions of a household.
+// Blank values are kept empty so saving them removes the field from the item.
+func (s *Store) Validate(householdID int64, raw map[int64]string) (Values, error) {
+	values := Values{}
+	if len(raw) == 0 {
+		return values, nil
+	}
+	definitions, err := s.Definitions(householdID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Definition)
+	for _, definition := range definitions {
+		byID[definition.ID] = definition
+	}
+	for fieldID, value := range raw {
+		definition, ok := byID[fieldID]
+		if !ok {
+			return nil, ErrFieldNotFound
+		}
+		if strings.TrimSpace(value) != "" {
+			if value, err = definition.Normalize(value); err != nil {
+				return nil, err
+			}
+		} else {
+			value = ""
+		}
+		values = append(values, Value{FieldID: fieldID, Name: definition.Name, Type: definition.Type, Value: value})
+	}
+	return values, nil
+}
+
+// SaveValues sets the fields of an item, removing the fields with an empty value.
+// Fields that are not in the values are left as they are.
+func SaveValues(tx *sql.Tx, itemID int64, values Values) error {
+	for _, value := range values {
+		var err error
+		if value.Value == "" {
+			_, err = tx.Exec("delete from item_field_values where item_id = ? and field_id = ?", itemID, value.FieldID)
+		} else {
+			// Numbers are stored as a number as well so they sort numerically.
+			var number sql.NullFloat64
+			if value.Type == TypeNumber {
+				number.Float64, _ = strconv.ParseFloat(value.Value, 64)
+				number.Valid = true
+			}
+			q := `
+				insert into item_field_values (item_id, field_id, value, number_value) values (?, ?, ?, ?)
+				on duplicate key update value = values(value), number_value = values(number_value)
+			`
+			_, err = tx.Exec(q, itemID, value.FieldID, value.Value, number)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyValues gives an item the same fields as another item, leaving out the fields that are not defined by the household.
+func CopyValues(tx *sql.Tx, fromItemID int64, toItemID int64, householdID int64) error {
+	q := `
+		insert into item_field_values (item_id, field_id, value, number_value)
+		select ?, v.field_id, v.value, v.number_value
+		from item_field_values v inner join fields f on f.id = v.field_id and f.household_id = ?
+		where v.item_id = ?
+	`
+	_, err := tx.Exec(q, toItemID, householdID, fromItemID)
+	return err
+}
+
+// RemoveForeignValues removes the fields of an item that are not defined by the household, such as after it
+// moved to a container of another household.
+func RemoveForeignValues(tx *sql.Tx, itemID int64, householdID int64) error {
+	q := `
+		delete v from item_field_values v inner join fields f on f.id = v.field_id
+		where v.item_id = ? and f.household_id != ?
+	`
+	_, err := tx.Exec(q, itemID, householdID)
+	return err
+}
+
+// ForItems retrieves the fields of items, ordered by field name, with a single query.
+func (s *Store) ForItems(itemIDs []int64) (map[int64]Values, error) {
+	byItem := make(map[int64]Values)
+	if len(itemIDs) == 0 {
+		return byItem, nil
+	}
+	args := make([]interface{}, len(itemIDs))
+	for i, ID := range itemIDs {
+		args[i] = ID
+	}
+	q := `
+		select v.item_id, f.id, f.name, f.type, v.value
+		from item_field_values v
+		inner join fields f on f.id = v.field_id
+		where v.item_id in ` + in(len(args)) + `
+		order by f.name
+	`
+	rows, err := s.DB.Query(q, args...)
+	if err != nil {
+		return byItem, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID int64
+		var value Value
+		if err = rows.Scan(&itemID, &value.FieldID, &value.Name, &value.Type, &value.Value); err != nil {
+			return byItem, err
+		}
+		byItem[itemID] = append(byItem[itemID], value)
+	}
+	return byItem, rows.Err()
+}
+
+// Condition is a SQL condition matching items, by the column holding their ID, that have every one of the values.
+func (v Values) Condition(column string) (string, []interface{}) {
+	conditions := make([]string, len(v))
+	args := make([]interface{}, 0, len(v)*2)
+	for i, value := range v {
+		conditions[i] = column + " in (select item_id from item_field_values where field_id = ? and value = ?)"
+		args = append(args, value.FieldID, value.Value)
+	}
+	return strings.Join(conditions, " and "), args
+}
+
+// SortExpression is a SQL expression to order items, by the column holding their ID, by the field.
+// Numbers sort numerically and every other type by its stored text, which keeps dates in order.
+func (d Definition) SortExpression(column string) string {
+	valueColumn := "value"
+	if d.Type == TypeNumber {
+		valueColumn = "number_value"
+	}
+	return fmt.Sprintf(
+		"(select sv.%v from item_field_values sv where sv.item_id = %v and sv.field_id = %d)",
+		valueColumn, column, d.ID)
+}
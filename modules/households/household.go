@@ -0,0 +1,57 @@
+package households
+
+import (
+	"time"
+)
+
+// Role is the level of access a member has to a household's inventory.
+type Role string
+
+const (
+	// RoleOwner can manage the household and its members.
+	RoleOwner Role = "owner"
+	// RoleEditor can create, modify and remove locations, containers and items.
+	RoleEditor Role = "editor"
+	// RoleViewer can only view the inventory.
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// IsValid reports if the role is a known role.
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Allows reports if the role grants at least the access of the required role.
+// An empty role (not a member) allows nothing.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// Household is a shared inventory of locations and containers.
+type Household struct {
+	ID   int64  `json:"id"`
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+	// IsPersonal marks the household every user gets for their own inventory.
+	IsPersonal bool `json:"is_personal"`
+	// Role is the role of the user the household was retrieved for.
+	Role     Role      `json:"role,omitempty"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// Member is a user with access to a household.
+type Member struct {
+	UserID  int64     `json:"user_id"`
+	Email   string    `json:"email"`
+	Role    Role      `json:"role"`
+	Created time.Time `json:"created"`
+}
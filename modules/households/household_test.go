@@ -0,0 +1,40 @@
+package households_test
+
+import (
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+)
+
+func TestRole_Allows(t *testing.T) {
+	cases := []struct {
+		role     households.Role
+		required households.Role
+		expected bool
+	}{
+		{households.RoleOwner, households.RoleOwner, true},
+		{households.RoleOwner, households.RoleViewer, true},
+		{households.RoleEditor, households.RoleEditor, true},
+		{households.RoleEditor, households.RoleOwner, false},
+		{households.RoleViewer, households.RoleViewer, true},
+		{households.RoleViewer, households.RoleEditor, false},
+		{"", households.RoleViewer, false},
+		{"admin", households.RoleViewer, false},
+	}
+	for _, c := range cases {
+		if result := c.role.Allows(c.required); result != c.expected {
+			t.Errorf("Expected %q allows %q to be %v", c.role, c.required, c.expected)
+		}
+	}
+}
+
+func TestRole_IsValid(t *testing.T) {
+	for _, role := range []households.Role{households.RoleOwner, households.RoleEditor, households.RoleViewer} {
+		if !role.IsValid() {
+			t.Errorf("Expected %q to be valid", role)
+		}
+	}
+	if households.Role("superuser").IsValid() {
+		t.Error("Expected unknown role to be invalid")
+	}
+}
@@ -0,0 +1,251 @@
+package households
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Invite states.
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusDeclined = "declined"
+	InviteStatusRevoked  = "revoked"
+)
+
+const tokenTypeInvite = "household_invite"
+
+var (
+	// ErrInvalidInvite is returned when an invite token is invalid, expired or no longer pending.
+	ErrInvalidInvite = errors.New("invalid or expired invite")
+	// ErrInviteEmailMismatch is returned when an invite is accepted by a user with a different email address.
+	ErrInviteEmailMismatch = errors.New("invite was sent to a different email address")
+	// ErrInviteNotFound is returned when revoking an invite that is not pending in the household.
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInvalidInviteEmail is returned when inviting a blank or malformed email address.
+	ErrInvalidInviteEmail = errors.New("invalid email address")
+)
+
+// Invite offers a role in a household to an email address.
+type Invite struct {
+	ID            int64     `json:"id"`
+	HouseholdID   int64     `json:"household_id"`
+	HouseholdName string    `json:"household_name"`
+	InvitedBy     int64     `json:"invited_by"`
+	Email         string    `json:"email"`
+	Role          Role      `json:"role"`
+	Status        string    `json:"status"`
+	Expires       time.Time `json:"expires"`
+	Created       time.Time `json:"created"`
+}
+
+const inviteFields = `
+	i.id, i.household_id, h.name, i.invited_by, i.email, i.role, i.status, i.expires, i.created
+`
+
+func scanInvite(row models.Scanner) (Invite, error) {
+	var invite Invite
+	err := row.Scan(
+		&invite.ID,
+		&invite.HouseholdID,
+		&invite.HouseholdName,
+		&invite.InvitedBy,
+		&invite.Email,
+		&invite.Role,
+		&invite.Status,
+		&invite.Expires,
+		&invite.Created)
+	return invite, err
+}
+
+// AddMember grants a user a role in a household. Existing members keep their role.
+func AddMember(tx *sql.Tx, householdID int64, userID int64, role Role) error {
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+	q := `
+		insert ignore into household_members (household_id, user_id, role, created) values (?, ?, ?, now())
+	`
+	_, err := tx.Exec(q, householdID, userID, role)
+	return err
+}
+
+// CreateInvite offers a role in the household to an email address.
+// Pending invites to the same address are replaced.
+func (s *Store) CreateInvite(householdID int64, invitedBy int64, email string, role Role, ttl time.Duration) (Invite, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || !strings.Contains(email, "@") {
+		return Invite{}, ErrInvalidInviteEmail
+	}
+	if !role.IsValid() {
+		return Invite{}, ErrInvalidRole
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return Invite{}, err
+	}
+	q := "update household_invites set status = ? where household_id = ? and email = ? and status = ?"
+	_, err = tx.Exec(q, InviteStatusRevoked, householdID, email, InviteStatusPending)
+	var res sql.Result
+	if err == nil {
+		q = `
+			insert into household_invites (household_id, invited_by, email, role, status, expires, created)
+			values (?, ?, ?, ?, ?, ?, now())
+		`
+		res, err = tx.Exec(q, householdID, invitedBy, email, role, InviteStatusPending, time.Now().Add(ttl))
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return Invite{}, err
+	}
+	ID, _ := res.LastInsertId()
+	return s.inviteByID(ID)
+}
+
+func (s *Store) inviteByID(ID int64) (Invite, error) {
+	q := `
+		select ` + inviteFields + `
+		from household_invites i
+		inner join households h on h.id = i.household_id
+		where i.id = ?
+	`
+	return scanInvite(s.DB.QueryRow(q, ID))
+}
+
+// PendingInvites lists the invites of a household that have not been answered, revoked or expired.
+func (s *Store) PendingInvites(householdID int64) ([]Invite, error) {
+	invites := []Invite{}
+	q := `
+		select ` + inviteFields + `
+		from household_invites i
+		inner join households h on h.id = i.household_id
+		where i.household_id = ? and i.status = ? and i.expires > ?
+		order by i.created desc
+	`
+	rows, err := s.DB.Query(q, householdID, InviteStatusPending, time.Now())
+	if err != nil {
+		return invites, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return invites, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// RevokeInvite withdraws a pending invite.
+func (s *Store) RevokeInvite(householdID int64, inviteID int64) error {
+	q := "update household_invites set status = ? where id = ? and household_id = ? and status = ?"
+	res, err := s.DB.Exec(q, InviteStatusRevoked, inviteID, householdID, InviteStatusPending)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// InviteToken signs a token that identifies the invite. It expires with the invite.
+func InviteToken(secret string, invite Invite) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":   tokenTypeInvite,
+		"id":    invite.ID,
+		"email": invite.Email,
+		"exp":   invite.Expires.Unix(),
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// InviteID verifies an invite token and returns the ID of the invite it identifies.
+func InviteID(secret string, token string) (int64, error) {
+	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidInvite
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !t.Valid {
+		return 0, ErrInvalidInvite
+	}
+	claims := t.Claims.(jwt.MapClaims)
+	if claims["typ"] != tokenTypeInvite {
+		return 0, ErrInvalidInvite
+	}
+	ID, _ := claims["id"].(float64)
+	return int64(ID), nil
+}
+
+// PendingInvite resolves a token to its invite as long as it can still be answered.
+func (s *Store) PendingInvite(secret string, token string) (Invite, error) {
+	ID, err := InviteID(secret, token)
+	if err != nil {
+		return Invite{}, err
+	}
+	invite, err := s.inviteByID(ID)
+	if err == sql.ErrNoRows || (err == nil && (invite.Status != InviteStatusPending || invite.Expires.Before(time.Now()))) {
+		return invite, ErrInvalidInvite
+	}
+	return invite, err
+}
+
+// AcceptInvite adds the user to the invite's household with the offered role.
+// The invite must have been sent to the user's email address.
+func (s *Store) AcceptInvite(secret string, token string, userID int64, email string) (Invite, error) {
+	invite, err := s.PendingInvite(secret, token)
+	if err != nil {
+		return invite, err
+	}
+	if !strings.EqualFold(invite.Email, strings.TrimSpace(email)) {
+		return invite, ErrInviteEmailMismatch
+	}
+	return invite, s.answerInvite(invite, InviteStatusAccepted, func(tx *sql.Tx) error {
+		return AddMember(tx, invite.HouseholdID, userID, invite.Role)
+	})
+}
+
+// DeclineInvite refuses an invite.
+func (s *Store) DeclineInvite(secret string, token string) error {
+	invite, err := s.PendingInvite(secret, token)
+	if err != nil {
+		return err
+	}
+	return s.answerInvite(invite, InviteStatusDeclined, nil)
+}
+
+func (s *Store) answerInvite(invite Invite, status string, fn func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	// Only the first answer counts when answered concurrently.
+	q := "update household_invites set status = ?, answered = now() where id = ? and status = ?"
+	res, err := tx.Exec(q, status, invite.ID, InviteStatusPending)
+	if err == nil {
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			err = ErrInvalidInvite
+		}
+	}
+	if err == nil && fn != nil {
+		err = fn(tx)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
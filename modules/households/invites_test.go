@@ -0,0 +1,47 @@
+package households_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestInviteToken(t *testing.T) {
+	invite := households.Invite{ID: 42, Email: "test@test.com", Expires: time.Now().Add(time.Hour)}
+	token, err := households.InviteToken("secret", invite)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ID, err := households.InviteID("secret", token)
+	if err != nil {
+		t.Error(err)
+	}
+	if ID != invite.ID {
+		t.Errorf("Expected invite ID %v but got %v", invite.ID, ID)
+	}
+	if _, err = households.InviteID("other", token); err != households.ErrInvalidInvite {
+		t.Error("Expected token signed with another secret to be rejected")
+	}
+}
+
+func TestInviteToken_Expired(t *testing.T) {
+	invite := households.Invite{ID: 42, Email: "test@test.com", Expires: time.Now().Add(-time.Minute)}
+	token, _ := households.InviteToken("secret", invite)
+	if _, err := households.InviteID("secret", token); err != households.ErrInvalidInvite {
+		t.Error("Expected expired invite token to be rejected")
+	}
+}
+
+func TestInviteToken_WrongType(t *testing.T) {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ": "access",
+		"id":  42,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	if _, err := households.InviteID("secret", token); err != households.ErrInvalidInvite {
+		t.Error("Expected token of another type to be rejected")
+	}
+}
@@ -0,0 +1,255 @@
+package households
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+var (
+	// ErrNotMember is returned when a user does not belong to a household.
+	ErrNotMember = errors.New("user is not a member of the household")
+	// ErrInvalidRole is returned for unknown roles.
+	ErrInvalidRole = errors.New("invalid household role")
+	// ErrLastOwner is returned when a change would leave a household without an owner.
+	ErrLastOwner = errors.New("a household must have at least one owner")
+	// ErrEmptyName is returned when a household has no name.
+	ErrEmptyName = errors.New("household must have a name")
+)
+
+// personalName is the name given to a user's personal household.
+const personalName = "Personal"
+
+// Store persists households and their memberships.
+type Store struct {
+	DB *sql.DB
+}
+
+// NewStore constructs a storage interface for households.
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+const householdFields = "h.id, h.uuid, h.name, h.personal_user_id is not null, m.role, h.created, h.modified"
+
+func scanHousehold(row models.Scanner) (Household, error) {
+	var household Household
+	err := row.Scan(
+		&household.ID,
+		&household.UUID,
+		&household.Name,
+		&household.IsPersonal,
+		&household.Role,
+		&household.Created,
+		&household.Modified)
+	return household, err
+}
+
+// Create adds a household owned by the user.
+func (s *Store) Create(userID int64, name string) (Household, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Household{}, ErrEmptyName
+	}
+	return s.create(userID, name, false)
+}
+
+func (s *Store) create(userID int64, name string, personal bool) (Household, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return Household{}, err
+	}
+	var personalUserID interface{}
+	if personal {
+		personalUserID = userID
+	}
+	q := `
+		insert into households (uuid, name, personal_user_id, created, modified)
+		values (uuid(), ?, ?, now(), now())
+	`
+	res, err := tx.Exec(q, name, personalUserID)
+	var ID int64
+	if err == nil {
+		ID, _ = res.LastInsertId()
+		q = "insert into household_members (household_id, user_id, role, created) values (?, ?, ?, now())"
+		_, err = tx.Exec(q, ID, userID, RoleOwner)
+	}
+	if err == nil && personal {
+		// Adopt inventory created before households existed.
+		for _, table := range []string{"locations", "containers"} {
+			q = "update " + table + " set household_id = ? where user_id = ? and household_id = 0"
+			if _, err = tx.Exec(q, ID, userID); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return Household{}, err
+	}
+	return s.ForUser(ID, userID)
+}
+
+// Personal retrieves the user's personal household, creating it on first use.
+func (s *Store) Personal(userID int64) (Household, error) {
+	q := `
+		select ` + householdFields + `
+		from households h
+		inner join household_members m on m.household_id = h.id and m.user_id = ?
+		where h.personal_user_id = ?
+	`
+	household, err := scanHousehold(s.DB.QueryRow(q, userID, userID))
+	if err != sql.ErrNoRows {
+		return household, err
+	}
+	household, err = s.create(userID, personalName, true)
+	if models.IsDuplicate(err) {
+		// Created concurrently by another request.
+		return scanHousehold(s.DB.QueryRow(q, userID, userID))
+	}
+	return household, err
+}
+
+// ForUser retrieves a household along with the user's role in it.
+func (s *Store) ForUser(householdID int64, userID int64) (Household, error) {
+	q := `
+		select ` + householdFields + `
+		from households h
+		inner join household_members m on m.household_id = h.id and m.user_id = ?
+		where h.id = ?
+	`
+	household, err := scanHousehold(s.DB.QueryRow(q, userID, householdID))
+	if err == sql.ErrNoRows {
+		err = ErrNotMember
+	}
+	return household, err
+}
+
+// AllForUser lists every household the user is a member of.
+func (s *Store) AllForUser(userID int64) ([]Household, error) {
+	list := []Household{}
+	q := `
+		select ` + householdFields + `
+		from households h
+		inner join household_members m on m.household_id = h.id and m.user_id = ?
+		order by h.personal_user_id is null, h.name
+	`
+	rows, err := s.DB.Query(q, userID)
+	if err != nil {
+		return list, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		household, err := scanHousehold(rows)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, household)
+	}
+	return list, rows.Err()
+}
+
+// Role retrieves the role of a user in a household.
+// Returns ErrNotMember if the user does not belong to the household.
+func (s *Store) Role(householdID int64, userID int64) (Role, error) {
+	var role Role
+	q := "select role from household_members where household_id = ? and user_id = ?"
+	err := s.DB.QueryRow(q, householdID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotMember
+	}
+	return role, err
+}
+
+// Rename changes the name of a household.
+func (s *Store) Rename(householdID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	_, err := s.DB.Exec("update households set name = ?, modified = now() where id = ?", name, householdID)
+	return err
+}
+
+// Members lists the members of a household.
+func (s *Store) Members(householdID int64) ([]Member, error) {
+	members := []Member{}
+	q := `
+		select m.user_id, u.email, m.role, m.created
+		from household_members m
+		inner join users u on u.id = m.user_id
+		where m.household_id = ?
+		order by m.created
+	`
+	rows, err := s.DB.Query(q, householdID)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		member := Member{}
+		if err = rows.Scan(&member.UserID, &member.Email, &member.Role, &member.Created); err != nil {
+			return members, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// SetRole changes the role of a member.
+func (s *Store) SetRole(householdID int64, userID int64, role Role) error {
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+	return s.changeMember(householdID, userID, func(tx *sql.Tx) error {
+		q := "update household_members set role = ? where household_id = ? and user_id = ?"
+		_, err := tx.Exec(q, role, householdID, userID)
+		return err
+	})
+}
+
+// RemoveMember revokes a user's access to a household.
+func (s *Store) RemoveMember(householdID int64, userID int64) error {
+	return s.changeMember(householdID, userID, func(tx *sql.Tx) error {
+		q := "delete from household_members where household_id = ? and user_id = ?"
+		_, err := tx.Exec(q, householdID, userID)
+		return err
+	})
+}
+
+// changeMember applies a change to a membership, refusing it if no owner would remain.
+func (s *Store) changeMember(householdID int64, userID int64, change func(tx *sql.Tx) error) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var role Role
+	q := "select role from household_members where household_id = ? and user_id = ? for update"
+	err = tx.QueryRow(q, householdID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		err = ErrNotMember
+	}
+	if err == nil {
+		err = change(tx)
+	}
+	if err == nil && role == RoleOwner {
+		var owners int
+		q = "select count(*) from household_members where household_id = ? and role = ? for update"
+		err = tx.QueryRow(q, householdID, RoleOwner).Scan(&owners)
+		if err == nil && owners == 0 {
+			err = ErrLastOwner
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package items
+
+import (
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/barcodes"
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+)
+
+// BarcodeMatch is an item found by its barcode along with the container holding it.
+type BarcodeMatch struct {
+	Item      ContainerItem        `json:"item"`
+	Container containers.Container `json:"container"`
+}
+
+// ByBarcode finds the items with a barcode in the containers of every household the user is a member of.
+// The value is normalized like barcodes.New, and UPC-A and EAN-13 forms of the same product code match each other.
+// Each item includes the full path to its container.
+func (c *Store) ByBarcode(userID int64, value string) ([]BarcodeMatch, error) {
+	variants := barcodes.Variants(value)
+	q := `
+		select ` + itemFields + `
+		from container_items ci
+		inner join containers c on c.id = ci.container_id
+		inner join household_members m on m.household_id = c.household_id and m.user_id = ?
+		where ci.barcode in (?` + strings.Repeat(",?", len(variants)-1) + `)
+		and ci.deleted_at is null and c.deleted_at is null
+		order by c.name, ci.body
+	`
+	args := []interface{}{userID}
+	for _, variant := range variants {
+		args = append(args, variant)
+	}
+	rows, err := c.DB.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := ContainerItems{}
+	var containerIDs []int64
+	for rows.Next() {
+		item, containerID, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		containerIDs = append(containerIDs, containerID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = c.attachDetails(list); err != nil {
+		return nil, err
+	}
+	matches := make([]BarcodeMatch, len(list))
+	containerModel := containers.NewStore(c.DB)
+	for i, containerID := range containerIDs {
+		container, err := containerModel.ByID(containerID)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Container = &container
+		list[i].Path, _ = containerModel.Path(container)
+		matches[i] = BarcodeMatch{Item: list[i], Container: container}
+	}
+	return matches, nil
+}
@@ -3,18 +3,39 @@ package items
 import (
 	"time"
 
+	"github.com/cjsaylor/boxmeup-go/modules/barcodes"
 	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+	"github.com/cjsaylor/boxmeup-go/modules/receipts"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
 )
 
+// MaxBodyLength is the number of characters the body of an item can hold.
+const MaxBodyLength = 100
+
 // ContainerItem represents a single item in a container
+// The purchase price and replacement value are per unit and in the currency of the item.
 type ContainerItem struct {
-	ID        int64                 `json:"id"`
-	Container *containers.Container `json:"-"`
-	UUID      string                `json:"uuid"`
-	Body      string                `json:"body"`
-	Quantity  int                   `json:"quantity"`
-	Created   time.Time             `json:"created"`
-	Modified  time.Time             `json:"modifed"`
+	ID               int64                 `json:"id"`
+	Container        *containers.Container `json:"-"`
+	UUID             string                `json:"uuid"`
+	Body             string                `json:"body"`
+	Quantity         int                   `json:"quantity"`
+	Barcode          string                `json:"barcode,omitempty"`
+	BarcodeType      barcodes.Type         `json:"barcode_type,omitempty"`
+	PurchasePrice    *valuation.Amount     `json:"purchase_price,omitempty"`
+	ReplacementValue *valuation.Amount     `json:"replacement_value,omitempty"`
+	Currency         string                `json:"currency,omitempty"`
+	PurchaseDate     *valuation.Date       `json:"purchase_date,omitempty"`
+	Receipt          *receipts.Receipt     `json:"receipt,omitempty"`
+	Created          time.Time             `json:"created"`
+	Modified         time.Time             `json:"modifed"`
+	Fields           fields.Values         `json:"fields"`
+	Photos           photos.Photos         `json:"photos"`
+	DeletedAt        *time.Time            `json:"deleted_at,omitempty"`
+	// Path locates the item's container: location > tote > bin. Only set for search results and the trash.
+	Path containers.Path `json:"path,omitempty"`
 }
 
 // ContainerItems is a collection of container items.
@@ -0,0 +1,135 @@
+package items
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+)
+
+var (
+	// ErrItemNotFound is returned when an item to move is not in the container it was expected in.
+	ErrItemNotFound = errors.New("item not found")
+	// ErrInvalidQuantity is returned when moving more than an item's quantity.
+	ErrInvalidQuantity = errors.New("quantity to move must be between 1 and the item's quantity")
+	// ErrSameContainer is returned when an item is already in the destination container.
+	ErrSameContainer = errors.New("item is already in the destination container")
+	// ErrDuplicateMove is returned when the same item is moved more than once in a request.
+	ErrDuplicateMove = errors.New("item can only be moved once per request")
+)
+
+// ItemMove describes moving an item, or part of its quantity, to another container.
+type ItemMove struct {
+	ItemID int64
+	// ContainerID is the container the item is expected to be in.
+	ContainerID int64
+	// Quantity splits the item, moving only part of it. Zero moves the whole item.
+	Quantity int
+}
+
+// MoveResult reports where a moved item ended up.
+type MoveResult struct {
+	ItemID int64 `json:"item_id"`
+	// MovedItemID is the item in the destination. It only differs from ItemID when the item was split.
+	MovedItemID int64 `json:"moved_item_id"`
+	Quantity    int   `json:"quantity"`
+	Split       bool  `json:"split"`
+}
+
+// Move transfers items to the destination container in a single transaction.
+// Moved items keep their UUID and created time. Moving part of an item's quantity
+// splits it: the quantity is taken from the original and a new item with the same fields is created in the destination.
+// Custom fields that the household of the destination doesn't define are dropped.
+// Either every item is moved or none are.
+func (c *Store) Move(moves []ItemMove, destination *containers.Container) ([]MoveResult, error) {
+	results := make([]MoveResult, 0, len(moves))
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return results, err
+	}
+	seen := make(map[int64]bool)
+	sources := make(map[int64]bool)
+	for _, move := range moves {
+		if seen[move.ItemID] {
+			err = ErrDuplicateMove
+			break
+		}
+		seen[move.ItemID] = true
+		var result MoveResult
+		result, err = moveItem(tx, move, destination)
+		if err != nil {
+			break
+		}
+		sources[move.ContainerID] = true
+		results = append(results, result)
+	}
+	if err == nil {
+		err = updateContainerItemCount(tx, destination.ID)
+	}
+	for containerID := range sources {
+		if err != nil {
+			break
+		}
+		err = updateContainerItemCount(tx, containerID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return []MoveResult{}, err
+	}
+	return results, nil
+}
+
+func moveItem(tx *sql.Tx, move ItemMove, destination *containers.Container) (MoveResult, error) {
+	result := MoveResult{ItemID: move.ItemID, MovedItemID: move.ItemID}
+	var containerID int64
+	var quantity int
+	q := "select container_id, quantity from container_items where id = ? and deleted_at is null for update"
+	err := tx.QueryRow(q, move.ItemID).Scan(&containerID, &quantity)
+	if err == sql.ErrNoRows || (err == nil && containerID != move.ContainerID) {
+		return result, ErrItemNotFound
+	} else if err != nil {
+		return result, err
+	}
+	if containerID == destination.ID {
+		return result, ErrSameContainer
+	}
+	if move.Quantity < 0 || move.Quantity > quantity {
+		return result, ErrInvalidQuantity
+	}
+	if move.Quantity == 0 || move.Quantity == quantity {
+		result.Quantity = quantity
+		q = "update container_items set container_id = ?, modified = now() where id = ?"
+		if _, err = tx.Exec(q, destination.ID, move.ItemID); err != nil {
+			return result, err
+		}
+		return result, fields.RemoveForeignValues(tx, move.ItemID, destination.HouseholdID)
+	}
+	result.Quantity = move.Quantity
+	result.Split = true
+	q = "update container_items set quantity = quantity - ?, modified = now() where id = ?"
+	if _, err = tx.Exec(q, move.Quantity, move.ItemID); err != nil {
+		return result, err
+	}
+	q = `
+		insert into container_items (
+			container_id, uuid, body, quantity, barcode, barcode_type,
+			purchase_price, replacement_value, currency, purchase_date, created, modified
+		)
+		select ?, uuid(), body, ?, barcode, barcode_type,
+			purchase_price, replacement_value, currency, purchase_date, now(), now()
+		from container_items where id = ?
+	`
+	res, err := tx.Exec(q, destination.ID, move.Quantity, move.ItemID)
+	if err == nil {
+		result.MovedItemID, err = res.LastInsertId()
+	}
+	if err == nil {
+		err = fields.CopyValues(tx, move.ItemID, result.MovedItemID, destination.HouseholdID)
+	}
+	return result, err
+}
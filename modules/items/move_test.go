@@ -0,0 +1,290 @@
+package items_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/sqlfixture"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	// @todo replace this with configured database from app
+	db, _ = sql.Open("mysql", "root:supersecret@tcp(localhost:3306)/bmu_test?parseTime=true")
+	defer db.Close()
+	os.Exit(m.Run())
+}
+
+// setup resets the fixtures so every test starts with 5 screws and a hammer in container 1, an empty container 2
+// and an empty container 3 in another household the user is not a member of.
+func setup(db *sql.DB) {
+	db.Exec("SET FOREIGN_KEY_CHECKS=0")
+	fixture := sqlfixture.New(db, sqlfixture.Tables{
+		sqlfixture.Table{
+			Name: "users",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":        1,
+					"email":     "test@test.com",
+					"is_active": 1,
+					"created":   "2017-05-15",
+					"modified":  "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "households",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":               1,
+					"uuid":             "0b9d7a57-4183-11e7-9cc8-0242ac120003",
+					"name":             "Personal",
+					"personal_user_id": 1,
+					"created":          "2017-05-15",
+					"modified":         "2017-05-15",
+				},
+				sqlfixture.Row{
+					"id":       2,
+					"uuid":     "0b9d7a57-4183-11e7-9cc8-0242ac120004",
+					"name":     "Cabin",
+					"created":  "2017-05-15",
+					"modified": "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "household_members",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"household_id": 1,
+					"user_id":      1,
+					"role":         "owner",
+					"created":      "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "containers",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":                   1,
+					"user_id":              1,
+					"household_id":         1,
+					"uuid":                 "4c3e9a0e-4184-11e7-9cc8-0242ac120003",
+					"code_number":          1,
+					"name":                 "Toolbox",
+					"container_item_count": 2,
+					"created":              "2017-05-15",
+					"modified":             "2017-05-15",
+				},
+				sqlfixture.Row{
+					"id":                   2,
+					"user_id":              1,
+					"household_id":         1,
+					"uuid":                 "4c3e9a0e-4184-11e7-9cc8-0242ac120004",
+					"code_number":          2,
+					"name":                 "Workbench",
+					"container_item_count": 0,
+					"created":              "2017-05-15",
+					"modified":             "2017-05-15",
+				},
+				sqlfixture.Row{
+					"id":                   3,
+					"user_id":              1,
+					"household_id":         2,
+					"uuid":                 "4c3e9a0e-4184-11e7-9cc8-0242ac120005",
+					"code_number":          1,
+					"name":                 "Shed",
+					"container_item_count": 0,
+					"created":              "2017-05-15",
+					"modified":             "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "container_items",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":           1,
+					"container_id": 1,
+					"uuid":         "8a4e5c1f-4184-11e7-9cc8-0242ac120003",
+					"body":         "Screws",
+					"quantity":     5,
+					"created":      "2017-05-15",
+					"modified":     "2017-05-15",
+				},
+				sqlfixture.Row{
+					"id":           2,
+					"container_id": 1,
+					"uuid":         "8a4e5c1f-4184-11e7-9cc8-0242ac120004",
+					"body":         "Hammer",
+					"quantity":     1,
+					"created":      "2017-05-15",
+					"modified":     "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "fields",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":           1,
+					"household_id": 1,
+					"name":         "Size",
+					"type":         "text",
+					"created":      "2017-05-15",
+					"modified":     "2017-05-15",
+				},
+				sqlfixture.Row{
+					"id":           2,
+					"household_id": 2,
+					"name":         "Color",
+					"type":         "text",
+					"created":      "2017-05-15",
+					"modified":     "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "item_field_values",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"item_id":  1,
+					"field_id": 1,
+					"value":    "M4",
+				},
+			},
+		},
+	})
+	fixture.Populate()
+	db.Exec("SET FOREIGN_KEY_CHECKS=1")
+}
+
+func itemCount(t *testing.T, containerID int64) int {
+	var count int
+	if err := db.QueryRow("select container_item_count from containers where id = ?", containerID).Scan(&count); err != nil {
+		t.Error(err)
+	}
+	return count
+}
+
+func TestStore_Move(t *testing.T) {
+	setup(db)
+	itemModel := items.NewStore(db)
+	destination := &containers.Container{ID: 2, HouseholdID: 1}
+	results, err := itemModel.Move([]items.ItemMove{
+		{ItemID: 2, ContainerID: 1},
+		{ItemID: 1, ContainerID: 1, Quantity: 2},
+	}, destination)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results but got %v", results)
+		return
+	}
+	if results[0].Split || results[0].MovedItemID != 2 || results[0].Quantity != 1 {
+		t.Errorf("Expected the hammer to move whole but got %v", results[0])
+	}
+	if !results[1].Split || results[1].MovedItemID == 1 || results[1].Quantity != 2 {
+		t.Errorf("Expected the screws to be split but got %v", results[1])
+		return
+	}
+	hammer, err := itemModel.ByID(2)
+	if err != nil || hammer.Container.ID != 2 {
+		t.Errorf("Expected the hammer in container 2 but got %v, %v", hammer.Container, err)
+	}
+	original, err := itemModel.ByID(1)
+	if err != nil || original.Container.ID != 1 || original.Quantity != 3 {
+		t.Errorf("Expected 3 screws to stay in container 1 but got %v, %v", original, err)
+	}
+	split, err := itemModel.ByID(results[1].MovedItemID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if split.Container.ID != 2 || split.Quantity != 2 || split.Body != "Screws" || split.UUID == original.UUID {
+		t.Errorf("Expected a new item of 2 screws in container 2 but got %v", split)
+	}
+	if len(split.Fields) != 1 || split.Fields[0].Value != "M4" {
+		t.Errorf("Expected the split item to keep its field values but got %v", split.Fields)
+	}
+	if count := itemCount(t, 1); count != 1 {
+		t.Errorf("Expected 1 item left in the source but got %v", count)
+	}
+	if count := itemCount(t, 2); count != 2 {
+		t.Errorf("Expected 2 items in the destination but got %v", count)
+	}
+}
+
+func TestStore_MoveRollback(t *testing.T) {
+	cases := map[error][]items.ItemMove{
+		items.ErrInvalidQuantity: {
+			{ItemID: 1, ContainerID: 1, Quantity: 2},
+			{ItemID: 2, ContainerID: 1, Quantity: 5},
+		},
+		items.ErrDuplicateMove: {
+			{ItemID: 1, ContainerID: 1, Quantity: 2},
+			{ItemID: 1, ContainerID: 1, Quantity: 2},
+		},
+		items.ErrItemNotFound: {
+			{ItemID: 1, ContainerID: 1, Quantity: 2},
+			{ItemID: 2, ContainerID: 2},
+		},
+	}
+	for expected, moves := range cases {
+		setup(db)
+		itemModel := items.NewStore(db)
+		results, err := itemModel.Move(moves, &containers.Container{ID: 2, HouseholdID: 1})
+		if err != expected || len(results) != 0 {
+			t.Errorf("Expected %v but got %v, %v", expected, results, err)
+			continue
+		}
+		screws, err := itemModel.ByID(1)
+		if err != nil || screws.Container.ID != 1 || screws.Quantity != 5 {
+			t.Errorf("Expected the split of the screws to be rolled back after %v but got %v, %v", expected, screws, err)
+		}
+		var count int
+		db.QueryRow("select count(*) from container_items where container_id = 2").Scan(&count)
+		if count != 0 || itemCount(t, 2) != 0 {
+			t.Errorf("Expected nothing in the destination after %v but got %v items", expected, count)
+		}
+	}
+}
+
+func TestStore_MoveSameContainer(t *testing.T) {
+	setup(db)
+	_, err := items.NewStore(db).Move([]items.ItemMove{{ItemID: 1, ContainerID: 1}}, &containers.Container{ID: 1, HouseholdID: 1})
+	if err != items.ErrSameContainer {
+		t.Errorf("Expected a same container error but got %v", err)
+	}
+}
+
+func TestStore_MoveToAnotherHousehold(t *testing.T) {
+	setup(db)
+	itemModel := items.NewStore(db)
+	destination := &containers.Container{ID: 3, HouseholdID: 2}
+	results, err := itemModel.Move([]items.ItemMove{{ItemID: 1, ContainerID: 1, Quantity: 2}}, destination)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	split, err := itemModel.ByID(results[0].MovedItemID)
+	if err != nil || len(split.Fields) != 0 {
+		t.Errorf("Expected the split item to leave the fields of the source household behind but got %v, %v", split.Fields, err)
+	}
+	if _, err = itemModel.Move([]items.ItemMove{{ItemID: 1, ContainerID: 1}}, destination); err != nil {
+		t.Error(err)
+		return
+	}
+	moved, err := itemModel.ByID(1)
+	if err != nil || moved.Container.ID != 3 || len(moved.Fields) != 0 {
+		t.Errorf("Expected the moved item to leave the fields of the source household behind but got %v, %v", moved.Fields, err)
+	}
+}
@@ -5,10 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
 	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+	"github.com/cjsaylor/boxmeup-go/modules/receipts"
 )
 
 // Store persists and queries container items
@@ -22,11 +28,14 @@ func NewStore(db *sql.DB) *Store {
 }
 
 // GetSortBy will retrieve a SortBy object taylored for container queries
-func (c *Store) GetSortBy(field string, direction models.SortType) models.SortBy {
+// Custom fields of the user's households are sorted by with "field:<id>".
+func (c *Store) GetSortBy(field string, direction models.SortType, userID int64) models.SortBy {
 	sortable := map[string]string{"modified": "modified", "body": "body", "quantity": "quantity"}
 	var sort models.SortBy
 	if _, ok := sortable[field]; ok {
 		sort.Field = field
+	} else if definition, err := c.sortableField(field, userID); err == nil {
+		sort.Field = definition.SortExpression("ci.id")
 	} else {
 		sort.Field = "modified"
 	}
@@ -39,37 +48,84 @@ func (c *Store) GetSortBy(field string, direction models.SortType) models.SortBy
 	return sort
 }
 
-// Create will persist a given container item.
+// sortableField resolves a "field:<id>" sort to a field of one of the user's households.
+func (c *Store) sortableField(field string, userID int64) (fields.Definition, error) {
+	if !strings.HasPrefix(field, "field:") {
+		return fields.Definition{}, fields.ErrFieldNotFound
+	}
+	ID, err := strconv.ParseInt(strings.TrimPrefix(field, "field:"), 10, 64)
+	if err != nil {
+		return fields.Definition{}, fields.ErrFieldNotFound
+	}
+	definition, err := fields.NewStore(c.DB).ByID(ID)
+	if err == nil {
+		role, _ := households.NewStore(c.DB).Role(definition.HouseholdID, userID)
+		if !role.Allows(households.RoleViewer) {
+			err = fields.ErrFieldNotFound
+		}
+	}
+	return definition, err
+}
+
+// Create will persist a given container item along with its fields.
 func (c *Store) Create(item *ContainerItem) error {
 	q := `
-		insert into container_items (container_id, uuid, body, quantity, created, modified)
-		values(?, uuid(), ?, ?, now(), now())
+		insert into container_items (
+			container_id, uuid, body, quantity, barcode, barcode_type,
+			purchase_price, replacement_value, currency, purchase_date, created, modified
+		)
+		values(?, uuid(), ?, ?, ?, ?, ?, ?, ?, ?, now(), now())
 	`
-	tx, _ := c.DB.Begin()
-	res, err := tx.Exec(q, item.Container.ID, item.Body, item.Quantity)
-	item.ID, _ = res.LastInsertId()
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(
+		q, item.Container.ID, item.Body, item.Quantity, item.Barcode, item.BarcodeType,
+		item.PurchasePrice, item.ReplacementValue, item.Currency, item.PurchaseDate)
+	if err == nil {
+		item.ID, err = res.LastInsertId()
+	}
+	if err == nil {
+		err = fields.SaveValues(tx, item.ID, item.Fields)
+	}
 	if err == nil {
 		err = updateContainerItemCount(tx, item.Container.ID)
 	}
 	if err == nil {
-		tx.Commit()
+		err = tx.Commit()
 	} else {
 		tx.Rollback()
 	}
-
 	return err
 }
 
-// Update a container item
+// Update a container item.
+// Only the fields included with the item are changed, and fields with an empty value are removed.
 func (c *Store) Update(item ContainerItem) error {
 	if item.ID == 0 {
 		return errors.New("can not update an item without it first being persisted")
 	}
 	q := `
-		update container_items set body = ?, quantity = ?, modified = now()
+		update container_items set body = ?, quantity = ?, barcode = ?, barcode_type = ?,
+			purchase_price = ?, replacement_value = ?, currency = ?, purchase_date = ?, modified = now()
 		where id = ?
 	`
-	_, err := c.DB.Exec(q, item.Body, item.Quantity, item.ID)
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		q, item.Body, item.Quantity, item.Barcode, item.BarcodeType,
+		item.PurchasePrice, item.ReplacementValue, item.Currency, item.PurchaseDate, item.ID)
+	if err == nil {
+		err = fields.SaveValues(tx, item.ID, item.Fields)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
 	return err
 }
 
@@ -79,7 +135,7 @@ func updateContainerItemCount(tx *sql.Tx, containerID int64) error {
 	q := `
 		update containers
 		set container_item_count = (
-			select count(*) from container_items where container_id = ?
+			select count(*) from container_items where container_id = ? and deleted_at is null
 		), modified = now()
 		where id = ?
 	`
@@ -87,11 +143,11 @@ func updateContainerItemCount(tx *sql.Tx, containerID int64) error {
 	return err
 }
 
-// Delete removes an item from a container
-func (c *Store) Delete(item ContainerItem) error {
-	q := "delete from container_items where id = ?"
+// Delete moves an item from a container to the trash of the user
+func (c *Store) Delete(item ContainerItem, userID int64) error {
+	q := "update container_items set deleted_at = now(), deleted_by = ? where id = ? and deleted_at is null"
 	tx, _ := c.DB.Begin()
-	_, err := tx.Exec(q, item.ID)
+	_, err := tx.Exec(q, userID, item.ID)
 	if err == nil {
 		err = updateContainerItemCount(tx, item.Container.ID)
 	}
@@ -117,32 +173,81 @@ func (r *PagedResponse) getItemIDMap() map[int64]*ContainerItem {
 	return mappedItems
 }
 
+// itemFields are the columns of an item aliased ci, in the order scanItem reads them.
+const itemFields = `ci.id, ci.container_id, ci.uuid, ci.body, ci.quantity, ci.barcode, ci.barcode_type,
+	ci.purchase_price, ci.replacement_value, ci.currency, ci.purchase_date, ci.created, ci.modified`
+
+// scanItem reads the itemFields of a row, followed by any extra columns, along with the ID of the item's container.
+func scanItem(row models.Scanner, extra ...interface{}) (ContainerItem, int64, error) {
+	var item ContainerItem
+	var containerID int64
+	dest := append([]interface{}{
+		&item.ID,
+		&containerID,
+		&item.UUID,
+		&item.Body,
+		&item.Quantity,
+		&item.Barcode,
+		&item.BarcodeType,
+		&item.PurchasePrice,
+		&item.ReplacementValue,
+		&item.Currency,
+		&item.PurchaseDate,
+		&item.Created,
+		&item.Modified,
+	}, extra...)
+	err := row.Scan(dest...)
+	return item, containerID, err
+}
+
 // ByID retrieves an item by its ID
 func (c *Store) ByID(ID int64) (ContainerItem, error) {
-	q := `
-		select id, container_id, uuid, body, quantity, created, modified
-		from container_items
-		where id = ?
-	`
-	item := ContainerItem{}
-	var containerID int64
-	err := c.DB.QueryRow(q, ID).Scan(&item.ID, &containerID, &item.UUID, &item.Body, &item.Quantity, &item.Created, &item.Modified)
+	q := "select " + itemFields + " from container_items ci where ci.id = ? and ci.deleted_at is null"
+	item, containerID, err := scanItem(c.DB.QueryRow(q, ID))
 	if err != nil {
 		return item, err
 	}
 	container, err := containers.NewStore(c.DB).ByID(containerID)
 	if err == nil {
 		item.Container = &container
+		list := ContainerItems{item}
+		err = c.attachDetails(list)
+		item = list[0]
 	}
 	return item, err
 }
 
+// attachDetails retrieves the fields, photos and receipts of the items with a query for each.
+func (c *Store) attachDetails(list ContainerItems) error {
+	IDs := make([]int64, len(list))
+	for i, item := range list {
+		IDs[i] = item.ID
+	}
+	values, err := fields.NewStore(c.DB).ForItems(IDs)
+	if err != nil {
+		return err
+	}
+	owned, err := photos.NewStore(c.DB, nil).ForOwners(photos.OwnerItem, IDs)
+	if err != nil {
+		return err
+	}
+	received, err := receipts.NewStore(c.DB, nil).ForItems(IDs)
+	for i := range list {
+		list[i].Fields = values[list[i].ID]
+		list[i].Photos = owned[list[i].ID]
+		if receipt, ok := received[list[i].ID]; ok {
+			list[i].Receipt = &receipt
+		}
+	}
+	return err
+}
+
 // GetContainerItems retrieves all items (paginated) from a container
 func (c *Store) GetContainerItems(container *containers.Container, sort models.SortBy, limit models.QueryLimit) (PagedResponse, error) {
 	q := `
-		select id, uuid, body, quantity, created, modified
-		from container_items
-		where container_id = ?
+		select SQL_CALC_FOUND_ROWS ` + itemFields + `
+		from container_items ci
+		where ci.container_id = ? and ci.deleted_at is null
 		order by %v %v
 		limit %v offset %v
 	`
@@ -154,28 +259,42 @@ func (c *Store) GetContainerItems(container *containers.Container, sort models.S
 	defer rows.Close()
 	response := PagedResponse{}
 	for rows.Next() {
-		item := ContainerItem{}
-		rows.Scan(&item.ID, &item.UUID, &item.Body, &item.Quantity, &item.Created, &item.Modified)
+		item, _, _ := scanItem(rows)
 		item.Container = container
 		response.Items = append(response.Items, item)
 	}
 	response.PagedResponse.RequestTotal = len(response.Items)
 	c.DB.QueryRow("select FOUND_ROWS()").Scan(&response.PagedResponse.Total)
+	if err = c.attachDetails(response.Items); err != nil {
+		return response, err
+	}
 	response.PagedResponse.CalculatePages(limit)
 	return response, rows.Err()
 }
 
-func (c *Store) SearchItems(userID int64, term string, sort models.SortBy, limit models.QueryLimit) (PagedResponse, error) {
+// SearchItems finds items in the containers of every household the user is a member of.
+// Items must match the term and have every one of the field values.
+// Each result includes the full path to its container.
+func (c *Store) SearchItems(userID int64, term string, filters fields.Values, sort models.SortBy, limit models.QueryLimit) (PagedResponse, error) {
 	q := `
-		select ci.id, container_id, ci.uuid, body, quantity, ci.created, ci.modified
+		select ` + itemFields + `
 		from container_items ci
-		inner join containers c on c.id = ci.container_id and c.user_id = ?
-		where body like concat('%%', ?, '%%')
+		inner join containers c on c.id = ci.container_id
+		inner join household_members m on m.household_id = c.household_id and m.user_id = ?
+		where body like concat('%%', ?, '%%') and ci.deleted_at is null and c.deleted_at is null
+		%v
 		order by %v %v
 		limit %v offset %v
 	`
-	q = fmt.Sprintf(q, sort.Field, sort.Direction, limit.Limit, limit.Offset)
-	rows, err := c.DB.Query(q, userID, term)
+	queryArgs := []interface{}{userID, term}
+	var filterFragment string
+	if len(filters) > 0 {
+		condition, filterArgs := filters.Condition("ci.id")
+		filterFragment = "and " + condition
+		queryArgs = append(queryArgs, filterArgs...)
+	}
+	q = fmt.Sprintf(q, filterFragment, sort.Field, sort.Direction, limit.Limit, limit.Offset)
+	rows, err := c.DB.Query(q, queryArgs...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -183,14 +302,15 @@ func (c *Store) SearchItems(userID int64, term string, sort models.SortBy, limit
 	response := PagedResponse{}
 	containerIDs := make(map[int64]int64)
 	for rows.Next() {
-		item := ContainerItem{}
-		var containerID int64
-		rows.Scan(&item.ID, &containerID, &item.UUID, &item.Body, &item.Quantity, &item.Created, &item.Modified)
+		item, containerID, _ := scanItem(rows)
 		containerIDs[item.ID] = containerID
 		response.Items = append(response.Items, item)
 	}
 	response.PagedResponse.RequestTotal = len(response.Items)
 	c.DB.QueryRow("select FOUND_ROWS()").Scan(&response.PagedResponse.Total)
+	if err = c.attachDetails(response.Items); err != nil {
+		return response, err
+	}
 	var wg sync.WaitGroup
 	wg.Add(len(containerIDs))
 	itemMap := response.getItemIDMap()
@@ -203,6 +323,7 @@ func (c *Store) SearchItems(userID int64, term string, sort models.SortBy, limit
 			// For the time being this is fine because we limit the maximum results to QueryLimit (20)
 			container, _ := containerModel.ByID(containerID)
 			item.Container = &container
+			item.Path, _ = containerModel.Path(container)
 		}(v, itemMap[k])
 	}
 	response.PagedResponse.CalculatePages(limit)
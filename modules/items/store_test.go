@@ -0,0 +1,19 @@
+package items_test
+
+import (
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+func TestStore_GetSortByField(t *testing.T) {
+	setup(db)
+	itemModel := items.NewStore(db)
+	if sort := itemModel.GetSortBy("field:1", models.ASC, 1); sort.Field == "modified" {
+		t.Errorf("Expected to sort by a field of the user's household but got %v", sort.Field)
+	}
+	if sort := itemModel.GetSortBy("field:2", models.ASC, 1); sort.Field != "modified" {
+		t.Errorf("Expected a field of another household to fall back to modified but got %v", sort.Field)
+	}
+}
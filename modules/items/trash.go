@@ -0,0 +1,111 @@
+package items
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+)
+
+// ErrContainerInTrash is returned when restoring an item whose container is in the trash or gone.
+var ErrContainerInTrash = errors.New("the container of this item must be restored first")
+
+// Trashed lists the items in the trash of a user, most recently deleted first.
+// Items whose container is still around include its path.
+// Items of households the user has since left are not included.
+func (c *Store) Trashed(userID int64) (ContainerItems, error) {
+	q := `
+		select ` + itemFields + `, ci.deleted_at
+		from container_items ci
+		inner join containers c on c.id = ci.container_id
+		inner join household_members m on m.household_id = c.household_id and m.user_id = ?
+		where ci.deleted_by = ? and ci.deleted_at is not null
+		order by ci.deleted_at desc
+	`
+	rows, err := c.DB.Query(q, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := ContainerItems{}
+	var containerIDs []int64
+	for rows.Next() {
+		var deletedAt time.Time
+		item, containerID, err := scanItem(rows, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.DeletedAt = &deletedAt
+		list = append(list, item)
+		containerIDs = append(containerIDs, containerID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	containerModel := containers.NewStore(c.DB)
+	for i, containerID := range containerIDs {
+		container, err := containerModel.ByID(containerID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		list[i].Container = &container
+		list[i].Path, _ = containerModel.Path(container)
+	}
+	return list, c.attachDetails(list)
+}
+
+// TrashedByID retrieves an item from the trash of a user.
+// The container is only set when it is not in the trash itself.
+func (c *Store) TrashedByID(ID int64, userID int64) (ContainerItem, error) {
+	q := `
+		select ` + itemFields + `, ci.deleted_at
+		from container_items ci
+		where ci.id = ? and ci.deleted_by = ? and ci.deleted_at is not null
+	`
+	var deletedAt time.Time
+	item, containerID, err := scanItem(c.DB.QueryRow(q, ID, userID), &deletedAt)
+	if err != nil {
+		return item, err
+	}
+	item.DeletedAt = &deletedAt
+	container, err := containers.NewStore(c.DB).ByID(containerID)
+	if err == sql.ErrNoRows {
+		return item, nil
+	}
+	item.Container = &container
+	return item, err
+}
+
+// Restore takes an item out of the trash and puts it back in its container.
+func (c *Store) Restore(item ContainerItem) error {
+	if item.Container == nil {
+		return ErrContainerInTrash
+	}
+	tx, err := c.DB.Begin()
+	if err != nil {
+		return err
+	}
+	q := "update container_items set deleted_at = null, deleted_by = null, modified = now() where id = ?"
+	_, err = tx.Exec(q, item.ID)
+	if err == nil {
+		err = updateContainerItemCount(tx, item.Container.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
+
+// PurgeTrash permanently deletes the items that were trashed before the given time.
+func (c *Store) PurgeTrash(before time.Time) (int64, error) {
+	res, err := c.DB.Exec("delete from container_items where deleted_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
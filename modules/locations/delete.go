@@ -0,0 +1,95 @@
+package locations
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// DeletePolicy decides what happens to the containers at a location when it is deleted.
+type DeletePolicy string
+
+const (
+	// DeleteRefuse keeps the location when containers are still at it.
+	DeleteRefuse DeletePolicy = "refuse"
+	// DeleteUnassign leaves the containers without a location.
+	DeleteUnassign DeletePolicy = "unassign"
+	// DeleteReassign moves the containers to another location.
+	DeleteReassign DeletePolicy = "reassign"
+)
+
+var (
+	// ErrInvalidPolicy is returned for an unknown delete policy.
+	ErrInvalidPolicy = errors.New("delete policy must be refuse, unassign or reassign")
+	// ErrLocationInUse is returned when refusing to delete a location that still has containers.
+	ErrLocationInUse = errors.New("location still has containers")
+	// ErrReassignTarget is returned when containers can't be reassigned to the chosen location.
+	ErrReassignTarget = errors.New("containers can only be reassigned to another location of the same household")
+)
+
+// ParseDeletePolicy reads a delete policy, defaulting to refusing.
+func ParseDeletePolicy(value string) (DeletePolicy, error) {
+	switch policy := DeletePolicy(value); policy {
+	case "":
+		return DeleteRefuse, nil
+	case DeleteRefuse, DeleteUnassign, DeleteReassign:
+		return policy, nil
+	}
+	return "", ErrInvalidPolicy
+}
+
+// DeleteResult reports what happened to the containers of a deleted location.
+type DeleteResult struct {
+	LocationID int64        `json:"location_id"`
+	Policy     DeletePolicy `json:"policy"`
+	// ContainerCount is the number of containers that were unassigned or reassigned.
+	ContainerCount int64 `json:"container_count"`
+	// ReassignedToID is the location the containers were moved to.
+	ReassignedToID int64 `json:"reassigned_to_id,omitempty"`
+}
+
+// detachContainers applies the delete policy to the containers at a location.
+// Containers in the trash follow along, so they are not restored into a deleted location.
+func detachContainers(tx *sql.Tx, location Location, result *DeleteResult) error {
+	q := "select count(*) from containers where location_id = ? and deleted_at is null"
+	if err := tx.QueryRow(q, location.ID).Scan(&result.ContainerCount); err != nil {
+		return err
+	}
+	switch result.Policy {
+	case DeleteRefuse:
+		if result.ContainerCount > 0 {
+			return ErrLocationInUse
+		}
+		return nil
+	case DeleteUnassign:
+	case DeleteReassign:
+		var householdID int64
+		q = "select household_id from locations where id = ? and deleted_at is null for update"
+		err := tx.QueryRow(q, result.ReassignedToID).Scan(&householdID)
+		if err == sql.ErrNoRows || (err == nil && (householdID != location.HouseholdID || result.ReassignedToID == location.ID)) {
+			return ErrReassignTarget
+		} else if err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidPolicy
+	}
+	q = "update containers set location_id = ?, modified = now() where location_id = ?"
+	if _, err := tx.Exec(q, result.ReassignedToID, location.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("update locations set container_count = 0 where id = ?", location.ID); err != nil {
+		return err
+	}
+	if result.ReassignedToID == 0 {
+		return nil
+	}
+	q = `
+		update locations
+		set container_count = (
+			select count(*) from containers where location_id = ? and deleted_at is null
+		), modified = now()
+		where id = ?
+	`
+	_, err := tx.Exec(q, result.ReassignedToID, result.ReassignedToID)
+	return err
+}
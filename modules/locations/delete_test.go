@@ -0,0 +1,25 @@
+package locations_test
+
+import (
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+)
+
+func TestParseDeletePolicy(t *testing.T) {
+	cases := map[string]locations.DeletePolicy{
+		"":         locations.DeleteRefuse,
+		"refuse":   locations.DeleteRefuse,
+		"unassign": locations.DeleteUnassign,
+		"reassign": locations.DeleteReassign,
+	}
+	for value, expected := range cases {
+		policy, err := locations.ParseDeletePolicy(value)
+		if err != nil || policy != expected {
+			t.Errorf("Expected %q to parse as %v but got %v, %v", value, expected, policy, err)
+		}
+	}
+	if _, err := locations.ParseDeletePolicy("cascade"); err != locations.ErrInvalidPolicy {
+		t.Errorf("Expected an invalid policy error but got %v", err)
+	}
+}
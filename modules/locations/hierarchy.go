@@ -0,0 +1,145 @@
+package locations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPathLength is the size of the path column holding the IDs of a location's ancestors.
+const maxPathLength = 255
+
+var (
+	// ErrLocationCycle is returned when a location would end up inside itself.
+	ErrLocationCycle = errors.New("a location can not be placed inside itself or its descendants")
+	// ErrParentHousehold is returned when the parent location belongs to another household.
+	ErrParentHousehold = errors.New("parent location belongs to another household")
+	// ErrTooDeep is returned when the location is nested too deep to be stored.
+	ErrTooDeep = errors.New("locations are nested too deep")
+)
+
+// Breadcrumb is a step in the path from the outermost location to a location.
+type Breadcrumb struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Breadcrumbs describe where a location is, outermost first: building > room > shelf.
+type Breadcrumbs []Breadcrumb
+
+func (b Breadcrumbs) String() string {
+	names := make([]string, len(b))
+	for i, crumb := range b {
+		names[i] = crumb.Name
+	}
+	return strings.Join(names, " > ")
+}
+
+// SubtreePrefix is the path shared by every location nested inside a location.
+func SubtreePrefix(path string, ID int64) string {
+	return path + strconv.FormatInt(ID, 10) + "/"
+}
+
+// AncestorIDs parses a path into the IDs of the ancestors it references, outermost first.
+func AncestorIDs(path string) []int64 {
+	IDs := []int64{}
+	for _, segment := range strings.Split(path, "/") {
+		if ID, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			IDs = append(IDs, ID)
+		}
+	}
+	return IDs
+}
+
+// SubtreeCondition is a SQL condition matching a location column against the subtree
+// of every location in the list of IDs. Each ID must be passed as an argument.
+func SubtreeCondition(column string, count int) string {
+	return fmt.Sprintf(`%v in (
+		select d.id from locations d inner join locations r
+		on d.id = r.id or d.path like concat(r.path, r.id, '/%%')
+		where r.id in (?%v) and d.deleted_at is null
+	)`, column, strings.Repeat(",?", count-1))
+}
+
+// parentPath validates a new parent for the location, returning the location's new path.
+func parentPath(tx *sql.Tx, location *Location) (string, error) {
+	if location.ParentID == 0 {
+		return "", nil
+	}
+	if location.ParentID == location.ID {
+		return "", ErrLocationCycle
+	}
+	var householdID int64
+	var path string
+	q := "select household_id, path from locations where id = ? and deleted_at is null"
+	if err := tx.QueryRow(q, location.ParentID).Scan(&householdID, &path); err != nil {
+		return "", err
+	}
+	if householdID != location.HouseholdID {
+		return "", ErrParentHousehold
+	}
+	if location.ID > 0 && strings.HasPrefix(path, SubtreePrefix(location.path, location.ID)) {
+		return "", ErrLocationCycle
+	}
+	path = SubtreePrefix(path, location.ParentID)
+	if len(path) >= maxPathLength {
+		return "", ErrTooDeep
+	}
+	return path, nil
+}
+
+// movePath rewrites the paths of nested locations when the locations above them change.
+func movePath(tx *sql.Tx, oldPrefix string, newPrefix string) error {
+	var deepest int
+	q := "select ifnull(max(length(path)), 0) from locations where path like concat(?, '%')"
+	if err := tx.QueryRow(q, oldPrefix).Scan(&deepest); err != nil {
+		return err
+	}
+	if deepest-len(oldPrefix)+len(newPrefix) >= maxPathLength {
+		return ErrTooDeep
+	}
+	q = "update locations set path = concat(?, substring(path, ?)) where path like concat(?, '%')"
+	_, err := tx.Exec(q, newPrefix, len(oldPrefix)+1, oldPrefix)
+	return err
+}
+
+// setBreadcrumbs resolves the breadcrumbs of the locations with a single query.
+func (l *Store) setBreadcrumbs(list []*Location) error {
+	names := make(map[int64]string)
+	var IDs []interface{}
+	for _, location := range list {
+		names[location.ID] = location.Name
+		for _, ID := range AncestorIDs(location.path) {
+			if _, ok := names[ID]; !ok {
+				names[ID] = ""
+				IDs = append(IDs, ID)
+			}
+		}
+	}
+	if len(IDs) > 0 {
+		rows, err := l.DB.Query("select id, name from locations where id in (?"+strings.Repeat(",?", len(IDs)-1)+")", IDs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var crumb Breadcrumb
+			if err = rows.Scan(&crumb.ID, &crumb.Name); err != nil {
+				return err
+			}
+			names[crumb.ID] = crumb.Name
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+	for _, location := range list {
+		location.Breadcrumbs = Breadcrumbs{}
+		for _, ID := range append(AncestorIDs(location.path), location.ID) {
+			location.Breadcrumbs = append(location.Breadcrumbs, Breadcrumb{ID: ID, Name: names[ID]})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package locations_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+)
+
+func TestSubtreePrefix(t *testing.T) {
+	if prefix := locations.SubtreePrefix("", 3); prefix != "3/" {
+		t.Errorf("Expected '3/' got %v", prefix)
+	}
+	if prefix := locations.SubtreePrefix("1/4/", 9); prefix != "1/4/9/" {
+		t.Errorf("Expected '1/4/9/' got %v", prefix)
+	}
+}
+
+func TestAncestorIDs(t *testing.T) {
+	if IDs := locations.AncestorIDs(""); len(IDs) != 0 {
+		t.Errorf("Expected no ancestors got %v", IDs)
+	}
+	if IDs := locations.AncestorIDs("1/4/"); !reflect.DeepEqual(IDs, []int64{1, 4}) {
+		t.Errorf("Expected [1 4] got %v", IDs)
+	}
+}
+
+func TestBreadcrumbs_String(t *testing.T) {
+	crumbs := locations.Breadcrumbs{{ID: 1, Name: "Garage"}, {ID: 4, Name: "Shelf 3"}}
+	if crumbs.String() != "Garage > Shelf 3" {
+		t.Errorf("Unexpected breadcrumbs %v", crumbs.String())
+	}
+}
+
+func TestSortableField_ContainerCount(t *testing.T) {
+	if field := locations.SortFieldContainerCount.String(); field != "container_count" {
+		t.Errorf("Expected 'container_count' got %v", field)
+	}
+	if field := locations.SortFieldTotalContainerCount.String(); field != "total_container_count" {
+		t.Errorf("Expected 'total_container_count' got %v", field)
+	}
+}
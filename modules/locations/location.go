@@ -4,25 +4,41 @@ import (
 	"time"
 
 	"github.com/cjsaylor/boxmeup-go/modules/users"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
 )
 
 // Location structure
+// ContainerCount only counts containers directly at the location, while TotalContainerCount
+// includes the containers of every location nested inside it. Value and TotalValue total the items
+// of those containers the same way.
 type Location struct {
-	ID             int64      `json:"id"`
-	User           users.User `json:"-"`
-	UUID           string     `json:"uuid"`
-	Name           string     `json:"name"`
-	Address        string     `json:"address"`
-	ContainerCount int        `json:"container_count"`
-	Created        time.Time  `json:"created"`
-	Modified       time.Time  `json:"modified"`
+	ID                  int64            `json:"id"`
+	User                users.User       `json:"-"`
+	HouseholdID         int64            `json:"household_id"`
+	ParentID            int64            `json:"parent_id"`
+	Breadcrumbs         Breadcrumbs      `json:"breadcrumbs"`
+	UUID                string           `json:"uuid"`
+	Name                string           `json:"name"`
+	Address             string           `json:"address"`
+	ContainerCount      int              `json:"container_count"`
+	TotalContainerCount int              `json:"total_container_count"`
+	Value               valuation.Totals `json:"value"`
+	TotalValue          valuation.Totals `json:"total_value"`
+	Created             time.Time        `json:"created"`
+	Modified            time.Time        `json:"modified"`
+	DeletedAt           *time.Time       `json:"deleted_at,omitempty"`
+
+	// path holds the IDs of the location's ancestors, e.g. "1/4/".
+	path string
 }
 
 // Locations group of locations
 type Locations []Location
 
 type LocationFilter struct {
-	User                  users.User
+	User users.User
+	// HouseholdID limits results to one household. Otherwise all households of the user are included.
+	HouseholdID           int64
 	ContainerID           int64
 	IsAttachedToContainer bool
 }
@@ -36,15 +36,24 @@ const (
 	SortFieldName
 	// SortFieldContainerCount indicates ordering by container count
 	SortFieldContainerCount
+	// SortFieldTotalContainerCount indicates ordering by container count including nested locations
+	SortFieldTotalContainerCount
 )
 
 var fields = [...]string{
 	"id",
 	"modified",
 	"name",
-	"countainer_count",
+	"container_count",
+	"total_container_count",
 }
 
+// totalContainerCount rolls up the container counts of a location aliased l and its nested locations.
+const totalContainerCount = `(
+	select sum(d.container_count) from locations d
+	where (d.id = l.id or d.path like concat(l.path, l.id, '/%')) and d.deleted_at is null
+)`
+
 // PagedResponse contains a group of locations and meta data for pagination
 type PagedResponse struct {
 	Locations     Locations            `json:"locations"`
@@ -83,75 +92,171 @@ func (l *Store) GetSortBy(sortField SortableField, direction models.SortType) mo
 
 // Create a location entry
 func (l *Store) Create(location *Location) error {
-	q := `
-		insert into locations (user_id, uuid, name, is_mappable, address, created, modified)
-		values (?, uuid(), ?, ?, ?, now(), now())
-	`
-	res, err := l.DB.Exec(q, location.User.ID, location.Name, location.Address != "", location.Address)
-	location.ID, _ = res.LastInsertId()
+	tx, err := l.DB.Begin()
+	if err != nil {
+		return err
+	}
+	location.path, err = parentPath(tx, location)
+	if err == nil {
+		q := `
+			insert into locations (user_id, household_id, parent_id, path, uuid, name, is_mappable, address, created, modified)
+			values (?, ?, ?, ?, uuid(), ?, ?, ?, now(), now())
+		`
+		var res sql.Result
+		res, err = tx.Exec(
+			q,
+			location.User.ID,
+			location.HouseholdID,
+			location.ParentID,
+			location.path,
+			location.Name,
+			location.Address != "",
+			location.Address)
+		if err == nil {
+			location.ID, _ = res.LastInsertId()
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
 	return err
 }
 
-// Update will update details of the provided location
+// Update will update details of the provided location.
+// Changing the parent moves every location nested inside it along.
 func (l *Store) Update(location *Location) error {
 	if location.ID == 0 {
 		return errors.New("location must already be stored")
 	}
-	q := `
-		update locations set name = ?, address = ?, modified = now() where id = ?
-	`
-	_, err := l.DB.Exec(q, location.Name, location.Address, location.ID)
+	tx, err := l.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var parentID int64
+	err = tx.QueryRow("select parent_id, path from locations where id = ? and deleted_at is null for update", location.ID).Scan(&parentID, &location.path)
+	if err == nil && parentID != location.ParentID {
+		var path string
+		path, err = parentPath(tx, location)
+		if err == nil {
+			err = movePath(tx, SubtreePrefix(location.path, location.ID), SubtreePrefix(path, location.ID))
+		}
+		location.path = path
+	}
+	if err == nil {
+		q := `
+			update locations set name = ?, address = ?, parent_id = ?, path = ?, modified = now() where id = ?
+		`
+		_, err = tx.Exec(q, location.Name, location.Address, location.ParentID, location.path, location.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
 	return err
 }
 
-// Delete will remove a location by ID.
-func (l *Store) Delete(ID int64) error {
-	q := "delete from locations where ID = ?"
-	_, err := l.DB.Exec(q, ID)
-	return err
+// Delete moves a location to the trash of the user.
+// The policy decides what happens to its containers, reassigning them to the location of reassignToID if asked to.
+// Locations directly inside it are moved up to its parent.
+// Either the location is deleted along with the change to its containers or nothing changes.
+func (l *Store) Delete(ID int64, userID int64, policy DeletePolicy, reassignToID int64) (DeleteResult, error) {
+	result := DeleteResult{LocationID: ID, Policy: policy}
+	if policy == DeleteReassign {
+		result.ReassignedToID = reassignToID
+	}
+	tx, err := l.DB.Begin()
+	if err != nil {
+		return result, err
+	}
+	location := Location{ID: ID}
+	q := "select household_id, parent_id, path from locations where id = ? and deleted_at is null for update"
+	err = tx.QueryRow(q, ID).Scan(&location.HouseholdID, &location.ParentID, &location.path)
+	if err == nil {
+		err = detachContainers(tx, location, &result)
+	}
+	if err == nil {
+		err = movePath(tx, SubtreePrefix(location.path, ID), location.path)
+	}
+	if err == nil {
+		q = "update locations set parent_id = ?, modified = now() where parent_id = ?"
+		_, err = tx.Exec(q, location.ParentID, ID)
+	}
+	if err == nil {
+		_, err = tx.Exec("update locations set deleted_at = now(), deleted_by = ? where id = ?", userID, ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return result, err
 }
 
 // ByID will return a location by its identifier.
 func (l *Store) ByID(ID int64) (Location, error) {
 	q := `
-		select id, user_id, uuid, name, address, container_count, created, modified
-		from locations where id = ?
+		select l.id, l.user_id, l.household_id, l.parent_id, l.path, l.uuid, l.name, l.address,
+			l.container_count, ` + totalContainerCount + `, l.created, l.modified
+		from locations l where l.id = ? and l.deleted_at is null
 	`
 	var location Location
 	var userID int64
 	err := l.DB.QueryRow(q, ID).Scan(
 		&location.ID,
 		&userID,
+		&location.HouseholdID,
+		&location.ParentID,
+		&location.path,
 		&location.UUID,
 		&location.Name,
 		&location.Address,
 		&location.ContainerCount,
+		&location.TotalContainerCount,
 		&location.Created,
 		&location.Modified)
+	if err == nil {
+		err = l.setBreadcrumbs([]*Location{&location})
+	}
+	if err == nil {
+		err = l.setValues([]*Location{&location})
+	}
 	if err == nil {
 		location.User, err = users.NewStore(l.DB).ByID(userID)
 	}
 	return location, err
 }
 
-// FilteredLocations will get all containers belonging to a user with filters
+// FilteredLocations will get all locations in the households of a user with filters
 func (l *Store) FilteredLocations(filter LocationFilter, sort models.SortBy, limit models.QueryLimit) (PagedResponse, error) {
 	q := `
-		select SQL_CALC_FOUND_ROWS id, uuid, name, address, container_count, created, modified
-		from locations
-		where user_id = ?
+		select SQL_CALC_FOUND_ROWS l.id, l.household_id, l.parent_id, l.path, l.uuid, l.name, l.address,
+			l.container_count, %v as total_container_count, l.created, l.modified
+		from locations l
+		where l.household_id in (select household_id from household_members where user_id = ?)
+			and l.deleted_at is null
+		%v
 		%v
 		order by %v %v
 		limit %v offset %v
 	`
+	queryArgs := []interface{}{filter.User.ID}
+	var householdFragment string
+	if filter.HouseholdID > 0 {
+		householdFragment = "and l.household_id = ?"
+		queryArgs = append(queryArgs, filter.HouseholdID)
+	}
 	var mustBeAttachedFragment string
 	if filter.IsAttachedToContainer {
-		mustBeAttachedFragment = "and container_count > 0"
+		mustBeAttachedFragment = "and l.container_count > 0"
 	} else {
 		mustBeAttachedFragment = ""
 	}
-	q = fmt.Sprintf(q, mustBeAttachedFragment, sort.Field, sort.Direction, limit.Limit, limit.Offset)
-	rows, err := l.DB.Query(q, filter.User.ID)
+	q = fmt.Sprintf(
+		q, totalContainerCount, householdFragment, mustBeAttachedFragment, sort.Field, sort.Direction, limit.Limit, limit.Offset)
+	rows, err := l.DB.Query(q, queryArgs...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -161,16 +266,30 @@ func (l *Store) FilteredLocations(filter LocationFilter, sort models.SortBy, lim
 		location := Location{}
 		rows.Scan(
 			&location.ID,
+			&location.HouseholdID,
+			&location.ParentID,
+			&location.path,
 			&location.UUID,
 			&location.Name,
 			&location.Address,
 			&location.ContainerCount,
+			&location.TotalContainerCount,
 			&location.Created,
 			&location.Modified)
 		response.Locations = append(response.Locations, location)
 	}
 	response.PagedResponse.RequestTotal = len(response.Locations)
 	l.DB.QueryRow("select FOUND_ROWS()").Scan(&response.PagedResponse.Total)
+	list := make([]*Location, len(response.Locations))
+	for i := range response.Locations {
+		list[i] = &response.Locations[i]
+	}
+	if err = l.setBreadcrumbs(list); err != nil {
+		return response, err
+	}
+	if err = l.setValues(list); err != nil {
+		return response, err
+	}
 	response.PagedResponse.CalculatePages(limit)
 	return response, rows.Err()
 }
@@ -37,12 +37,37 @@ func setup(db *sql.DB) {
 				},
 			},
 		},
+		sqlfixture.Table{
+			Name: "households",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"id":               1,
+					"uuid":             "0b9d7a57-4183-11e7-9cc8-0242ac120003",
+					"name":             "Personal",
+					"personal_user_id": 1,
+					"created":          "2017-05-15",
+					"modified":         "2017-05-15",
+				},
+			},
+		},
+		sqlfixture.Table{
+			Name: "household_members",
+			Rows: sqlfixture.Rows{
+				sqlfixture.Row{
+					"household_id": 1,
+					"user_id":      1,
+					"role":         "owner",
+					"created":      "2017-05-15",
+				},
+			},
+		},
 		sqlfixture.Table{
 			Name: "locations",
 			Rows: sqlfixture.Rows{
 				sqlfixture.Row{
 					"id":              1,
 					"user_id":         1,
+					"household_id":    1,
 					"uuid":            "ff1eda35-4183-11e7-9cc8-0242ac120003",
 					"name":            "My Garage",
 					"address":         "",
@@ -54,6 +79,7 @@ func setup(db *sql.DB) {
 				sqlfixture.Row{
 					"id":              2,
 					"user_id":         1,
+					"household_id":    1,
 					"uuid":            "ff1eda35-4183-11e7-9cc8-0242ac120004",
 					"name":            "Basement",
 					"address":         "",
@@ -64,6 +90,9 @@ func setup(db *sql.DB) {
 				},
 			},
 		},
+		sqlfixture.Table{
+			Name: "containers",
+		},
 	})
 	fixture.Populate()
 	db.Exec("SET FOREIGN_KEY_CHECKS=1")
@@ -122,8 +151,9 @@ func TestStore_Create(t *testing.T) {
 		User: users.User{
 			ID: 1,
 		},
-		Name:    "Some New Location",
-		Address: "123 Easy St.",
+		HouseholdID: 1,
+		Name:        "Some New Location",
+		Address:     "123 Easy St.",
 	}
 	err := locationModel.Create(&location)
 	if err != nil {
@@ -170,7 +200,7 @@ func TestStore_Update(t *testing.T) {
 
 func TestStore_Delete(t *testing.T) {
 	locationModel := locations.NewStore(db)
-	err := locationModel.Delete(1)
+	_, err := locationModel.Delete(1, 1, locations.DeleteUnassign, 0)
 	if err != nil {
 		t.Error(err)
 		return
@@ -206,3 +236,149 @@ func TestStore_FilteredLocations(t *testing.T) {
 		return
 	}
 }
+
+func TestStore_Nesting(t *testing.T) {
+	locationModel := locations.NewStore(db)
+	user := users.User{ID: 1}
+	building := locations.Location{User: user, HouseholdID: 1, Name: "House"}
+	if err := locationModel.Create(&building); err != nil {
+		t.Error(err)
+		return
+	}
+	room := locations.Location{User: user, HouseholdID: 1, Name: "Garage", ParentID: building.ID}
+	if err := locationModel.Create(&room); err != nil {
+		t.Error(err)
+		return
+	}
+	shelf := locations.Location{User: user, HouseholdID: 1, Name: "Shelf 3", ParentID: room.ID}
+	if err := locationModel.Create(&shelf); err != nil {
+		t.Error(err)
+		return
+	}
+	result, err := locationModel.ByID(shelf.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result.Breadcrumbs.String() != "House > Garage > Shelf 3" {
+		t.Errorf("Unexpected breadcrumbs %v", result.Breadcrumbs.String())
+	}
+	building.ParentID = shelf.ID
+	if err = locationModel.Update(&building); err != locations.ErrLocationCycle {
+		t.Errorf("Expected a cycle error but got %v", err)
+	}
+	room.ParentID = 0
+	if err = locationModel.Update(&room); err != nil {
+		t.Error(err)
+		return
+	}
+	result, err = locationModel.ByID(shelf.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result.Breadcrumbs.String() != "Garage > Shelf 3" {
+		t.Errorf("Expected the shelf to move along with its parent but got %v", result.Breadcrumbs.String())
+	}
+}
+
+func TestStore_Restore(t *testing.T) {
+	locationModel := locations.NewStore(db)
+	trashed, err := locationModel.Trashed(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(trashed) != 1 || trashed[0].ID != 1 {
+		t.Errorf("Expected location 1 in the trash but got %v", trashed)
+		return
+	}
+	if err = locationModel.Restore(trashed[0]); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = locationModel.ByID(1); err != nil {
+		t.Errorf("Expected the restored location to be found but got %v", err)
+	}
+	if _, err = locationModel.TrashedByID(1, 1); err != sql.ErrNoRows {
+		t.Errorf("Expected the location to have left the trash but got %v", err)
+	}
+}
+
+func createContainers(t *testing.T, locationID int64, count int) {
+	q := `
+		insert into containers (user_id, household_id, location_id, uuid, name, created, modified)
+		values (1, 1, ?, uuid(), 'Box', now(), now())
+	`
+	for i := 0; i < count; i++ {
+		if _, err := db.Exec(q, locationID); err != nil {
+			t.Error(err)
+		}
+	}
+	db.Exec("update locations set container_count = ? where id = ?", count, locationID)
+}
+
+func TestStore_DeleteRefuse(t *testing.T) {
+	locationModel := locations.NewStore(db)
+	shed := locations.Location{User: users.User{ID: 1}, HouseholdID: 1, Name: "Shed"}
+	if err := locationModel.Create(&shed); err != nil {
+		t.Error(err)
+		return
+	}
+	createContainers(t, shed.ID, 1)
+	result, err := locationModel.Delete(shed.ID, 1, locations.DeleteRefuse, 0)
+	if err != locations.ErrLocationInUse {
+		t.Errorf("Expected the location to be in use but got %v", err)
+	}
+	if result.ContainerCount != 1 {
+		t.Errorf("Expected 1 container to be reported but got %v", result.ContainerCount)
+	}
+	if _, err = locationModel.ByID(shed.ID); err != nil {
+		t.Errorf("Expected the location to be kept but got %v", err)
+	}
+}
+
+func TestStore_DeleteReassign(t *testing.T) {
+	locationModel := locations.NewStore(db)
+	user := users.User{ID: 1}
+	attic := locations.Location{User: user, HouseholdID: 1, Name: "Attic"}
+	storage := locations.Location{User: user, HouseholdID: 1, Name: "Storage Unit"}
+	elsewhere := locations.Location{User: user, HouseholdID: 2, Name: "Other Household"}
+	for _, location := range []*locations.Location{&attic, &storage, &elsewhere} {
+		if err := locationModel.Create(location); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	createContainers(t, attic.ID, 2)
+	for _, targetID := range []int64{elsewhere.ID, attic.ID, 0} {
+		if _, err := locationModel.Delete(attic.ID, 1, locations.DeleteReassign, targetID); err != locations.ErrReassignTarget {
+			t.Errorf("Expected reassigning to %v to fail but got %v", targetID, err)
+		}
+	}
+	if _, err := locationModel.ByID(attic.ID); err != nil {
+		t.Errorf("Expected the location to be kept after a failed reassign but got %v", err)
+		return
+	}
+	result, err := locationModel.Delete(attic.ID, 1, locations.DeleteReassign, storage.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result.ContainerCount != 2 || result.ReassignedToID != storage.ID {
+		t.Errorf("Expected 2 containers reassigned to %v but got %v", storage.ID, result)
+	}
+	target, err := locationModel.ByID(storage.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if target.ContainerCount != 2 {
+		t.Errorf("Expected the target to count 2 containers but got %v", target.ContainerCount)
+	}
+	var remaining int
+	db.QueryRow("select count(*) from containers where location_id = ?", attic.ID).Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected no containers left at the deleted location but got %v", remaining)
+	}
+}
@@ -0,0 +1,122 @@
+package locations
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+const trashFields = "id, household_id, parent_id, path, uuid, name, address, container_count, created, modified, deleted_at"
+
+func scanTrashed(row models.Scanner) (Location, error) {
+	var location Location
+	var deletedAt time.Time
+	err := row.Scan(
+		&location.ID,
+		&location.HouseholdID,
+		&location.ParentID,
+		&location.path,
+		&location.UUID,
+		&location.Name,
+		&location.Address,
+		&location.ContainerCount,
+		&location.Created,
+		&location.Modified,
+		&deletedAt)
+	location.DeletedAt = &deletedAt
+	return location, err
+}
+
+// Trashed lists the locations in the trash of a user, most recently deleted first.
+// Locations of households the user has since left are not included.
+func (l *Store) Trashed(userID int64) (Locations, error) {
+	q := `
+		select ` + trashFields + `
+		from locations
+		where deleted_by = ? and deleted_at is not null
+			and household_id in (select household_id from household_members where user_id = ?)
+		order by deleted_at desc
+	`
+	rows, err := l.DB.Query(q, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := Locations{}
+	for rows.Next() {
+		location, err := scanTrashed(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, location)
+	}
+	return list, rows.Err()
+}
+
+// TrashedByID retrieves a location from the trash of a user.
+func (l *Store) TrashedByID(ID int64, userID int64) (Location, error) {
+	q := "select " + trashFields + " from locations where id = ? and deleted_by = ? and deleted_at is not null"
+	return scanTrashed(l.DB.QueryRow(q, ID, userID))
+}
+
+// Restore takes a location out of the trash.
+// It goes back inside its parent, or to the top level when the parent is gone.
+func (l *Store) Restore(location Location) error {
+	tx, err := l.DB.Begin()
+	if err != nil {
+		return err
+	}
+	q := "select parent_id, path from locations where id = ? and deleted_at is not null for update"
+	err = tx.QueryRow(q, location.ID).Scan(&location.ParentID, &location.path)
+	var path string
+	if err == nil {
+		path, err = parentPath(tx, &location)
+		if err == sql.ErrNoRows {
+			location.ParentID, path, err = 0, "", nil
+		}
+	}
+	if err == nil {
+		q = `
+			update locations set parent_id = ?, path = ?, deleted_at = null, deleted_by = null,
+				container_count = (select count(*) from containers where location_id = ? and deleted_at is null),
+				modified = now()
+			where id = ?
+		`
+		_, err = tx.Exec(q, location.ParentID, path, location.ID, location.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
+
+// PurgeTrash permanently deletes the locations that were trashed before the given time.
+// Containers still referring to them are left without a location.
+func (l *Store) PurgeTrash(before time.Time) (int64, error) {
+	tx, err := l.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	q := `
+		update containers set location_id = 0, modified = now()
+		where location_id in (select id from locations where deleted_at < ?)
+	`
+	var purged int64
+	_, err = tx.Exec(q, before)
+	if err == nil {
+		var res sql.Result
+		res, err = tx.Exec("delete from locations where deleted_at < ?", before)
+		if err == nil {
+			purged, _ = res.RowsAffected()
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return purged, err
+}
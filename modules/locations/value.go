@@ -0,0 +1,46 @@
+package locations
+
+import (
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+// setValues totals the value of the items at each location, with and without its nested locations.
+func (l *Store) setValues(list []*Location) error {
+	if len(list) == 0 {
+		return nil
+	}
+	IDs := make([]int64, len(list))
+	args := make([]interface{}, len(list))
+	for i, location := range list {
+		IDs[i] = location.ID
+		args[i] = location.ID
+	}
+	in := "(?" + strings.Repeat(",?", len(IDs)-1) + ")"
+	source := `
+		container_items ci
+		inner join containers c on c.id = ci.container_id
+		where c.location_id in ` + in + ` and ci.deleted_at is null and c.deleted_at is null
+	`
+	direct, err := valuation.Rollup(l.DB, "c.location_id", source, IDs, args...)
+	if err != nil {
+		return err
+	}
+	source = `
+		container_items ci
+		inner join containers c on c.id = ci.container_id
+		inner join locations d on d.id = c.location_id and d.deleted_at is null
+		inner join locations r on d.id = r.id or d.path like concat(r.path, r.id, '/%')
+		where r.id in ` + in + ` and ci.deleted_at is null and c.deleted_at is null
+	`
+	total, err := valuation.Rollup(l.DB, "r.id", source, IDs, args...)
+	if err != nil {
+		return err
+	}
+	for _, location := range list {
+		location.Value = direct[location.ID]
+		location.TotalValue = total[location.ID]
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an outgoing plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(message Message) error
+}
+
+// SMTPMailer delivers messages through an SMTP relay.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message through the configured SMTP server.
+func (m SMTPMailer) Send(message Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(
+		fmt.Sprintf("%v:%v", m.Host, m.Port),
+		auth,
+		m.From,
+		[]string{message.To},
+		format(m.From, message))
+}
+
+// MemoryMailer keeps sent messages in memory. Useful for testing.
+type MemoryMailer struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+// Send records the message.
+func (m *MemoryMailer) Send(message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages retrieves all messages sent so far.
+func (m *MemoryMailer) Messages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// FileMailer writes each message to a file in Dir. Useful for local development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send writes the message to a new file in the configured directory.
+func (m FileMailer) Send(message Message) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%v-%v.eml", time.Now().UnixNano(), sanitize(message.To))
+	return ioutil.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0644)
+}
+
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, address)
+}
+
+// headerSanitizer prevents header injection through user supplied values.
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func format(from string, message Message) []byte {
+	headers := []string{
+		"From: " + headerSanitizer.Replace(from),
+		"To: " + headerSanitizer.Replace(message.To),
+		"Subject: " + headerSanitizer.Replace(message.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + message.Body)
+}
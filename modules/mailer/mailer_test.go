@@ -0,0 +1,46 @@
+package mailer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/mailer"
+)
+
+func TestMemoryMailer_Send(t *testing.T) {
+	m := &mailer.MemoryMailer{}
+	m.Send(mailer.Message{To: "test@test.com", Subject: "Hello", Body: "World"})
+	messages := m.Messages()
+	if len(messages) != 1 {
+		t.Errorf("Expected 1 message but got %v", len(messages))
+		return
+	}
+	if messages[0].To != "test@test.com" {
+		t.Errorf("Expected recipient test@test.com but got %v", messages[0].To)
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bmu-mail")
+	defer os.RemoveAll(dir)
+	m := mailer.FileMailer{Dir: dir, From: "noreply@boxmeupapp.com"}
+	err := m.Send(mailer.Message{To: "test@test.com", Subject: "Hello\r\nBcc: evil@test.com", Body: "World"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected 1 file but got %v", len(files))
+		return
+	}
+	contents, _ := ioutil.ReadFile(dir + "/" + files[0].Name())
+	if strings.Contains(string(contents), "\r\nBcc:") {
+		t.Error("Expected header injection to be stripped.")
+	}
+	if !strings.HasSuffix(string(contents), "\r\n\r\nWorld") {
+		t.Errorf("Unexpected message contents: %q", contents)
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/go-sql-driver/mysql"
+
+// Scanner reads the columns of a query result, such as *sql.Row or *sql.Rows.
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// IsDuplicate reports whether an error is MySQL refusing a row that duplicates a unique key.
+func IsDuplicate(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == 1062
+}
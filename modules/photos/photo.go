@@ -0,0 +1,44 @@
+package photos
+
+import (
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+)
+
+// Owner types that photos can be attached to.
+const (
+	OwnerContainer = "container"
+	OwnerItem      = "item"
+)
+
+// Photo is an image attached to a container or item.
+type Photo struct {
+	ID           int64     `json:"-"`
+	UUID         string    `json:"uuid"`
+	OwnerType    string    `json:"-"`
+	OwnerID      int64     `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Created      time.Time `json:"created"`
+}
+
+// Photos is a group of photos
+type Photos []Photo
+
+func (p *Photo) setURLs() {
+	p.URL = "/api/photo/" + p.UUID
+	p.ThumbnailURL = p.URL + "/thumbnail"
+}
+
+func (p Photo) key() string {
+	return blobs.Key("photos", p.UUID)
+}
+
+func (p Photo) thumbnailKey() string {
+	return blobs.Key("photos", p.UUID+"_thumb.jpg")
+}
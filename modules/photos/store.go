@@ -0,0 +1,211 @@
+package photos
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"image"
+	// Decoders for the accepted upload formats.
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+const (
+	// ThumbnailSize is the maximum width and height of thumbnails.
+	ThumbnailSize = 320
+	// MaxPixels limits the dimensions of uploads so a small file can't decode into a huge image.
+	MaxPixels = 50 * 1000 * 1000
+)
+
+var (
+	// ErrUnsupportedImage is returned for uploads that are not JPEG, PNG or GIF images.
+	ErrUnsupportedImage = errors.New("photos must be JPEG, PNG or GIF images")
+	// ErrImageTooLarge is returned for images with more than MaxPixels pixels.
+	ErrImageTooLarge = errors.New("photo dimensions are too large")
+	// ErrPhotoNotFound is returned when a photo does not exist.
+	ErrPhotoNotFound = errors.New("photo not found")
+)
+
+// Store persists photos: their details in the database and their images in a blob store.
+type Store struct {
+	DB    *sql.DB
+	Blobs blobs.Store
+}
+
+// NewStore constructs a storage interface for photos.
+// The blob store may be nil when only reading photo details.
+func NewStore(db *sql.DB, blobStore blobs.Store) *Store {
+	return &Store{DB: db, Blobs: blobStore}
+}
+
+// Create stores an uploaded image along with a generated thumbnail and attaches it to its owner.
+func (s *Store) Create(ownerType string, ownerID int64, data []byte) (Photo, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Photo{}, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Photo{}, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Photo{}, ErrUnsupportedImage
+	}
+	var thumbnail bytes.Buffer
+	if err = jpeg.Encode(&thumbnail, Thumbnail(img, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return Photo{}, err
+	}
+	photo := Photo{
+		OwnerType:   ownerType,
+		OwnerID:     ownerID,
+		ContentType: "image/" + format,
+		Size:        int64(len(data)),
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+	if photo.UUID, err = blobs.NewUUID(); err != nil {
+		return photo, err
+	}
+	if err = s.Blobs.Put(photo.key(), photo.ContentType, data); err != nil {
+		return photo, err
+	}
+	if err = s.Blobs.Put(photo.thumbnailKey(), "image/jpeg", thumbnail.Bytes()); err != nil {
+		s.Blobs.Delete(photo.key())
+		return photo, err
+	}
+	q := `
+		insert into photos (uuid, owner_type, owner_id, content_type, size, width, height, created)
+		values (?, ?, ?, ?, ?, ?, ?, now())
+	`
+	res, err := s.DB.Exec(
+		q, photo.UUID, photo.OwnerType, photo.OwnerID, photo.ContentType, photo.Size, photo.Width, photo.Height)
+	if err != nil {
+		s.deleteBlobs(photo)
+		return photo, err
+	}
+	photo.ID, _ = res.LastInsertId()
+	return s.ByUUID(photo.UUID)
+}
+
+const photoFields = "id, uuid, owner_type, owner_id, content_type, size, width, height, created"
+
+func scanPhoto(row models.Scanner) (Photo, error) {
+	var photo Photo
+	err := row.Scan(
+		&photo.ID,
+		&photo.UUID,
+		&photo.OwnerType,
+		&photo.OwnerID,
+		&photo.ContentType,
+		&photo.Size,
+		&photo.Width,
+		&photo.Height,
+		&photo.Created)
+	photo.setURLs()
+	return photo, err
+}
+
+// ByUUID retrieves a photo.
+func (s *Store) ByUUID(UUID string) (Photo, error) {
+	photo, err := scanPhoto(s.DB.QueryRow("select "+photoFields+" from photos where uuid = ?", UUID))
+	if err == sql.ErrNoRows {
+		err = ErrPhotoNotFound
+	}
+	return photo, err
+}
+
+// ForOwners retrieves the photos of many containers or items at once, keyed by owner ID.
+// Every requested owner is present in the result.
+func (s *Store) ForOwners(ownerType string, ownerIDs []int64) (map[int64]Photos, error) {
+	owned := make(map[int64]Photos, len(ownerIDs))
+	if len(ownerIDs) == 0 {
+		return owned, nil
+	}
+	args := []interface{}{ownerType}
+	for _, ID := range ownerIDs {
+		owned[ID] = Photos{}
+		args = append(args, ID)
+	}
+	q := "select " + photoFields + " from photos where owner_type = ? and owner_id in (?" +
+		strings.Repeat(",?", len(ownerIDs)-1) + ") order by id"
+	rows, err := s.DB.Query(q, args...)
+	if err != nil {
+		return owned, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return owned, err
+		}
+		owned[photo.OwnerID] = append(owned[photo.OwnerID], photo)
+	}
+	return owned, rows.Err()
+}
+
+// HouseholdID resolves the household of the container or item a photo belongs to.
+// Photos of containers and items in the trash are treated as missing.
+func (s *Store) HouseholdID(photo Photo) (int64, error) {
+	var householdID int64
+	var err error
+	switch photo.OwnerType {
+	case OwnerContainer:
+		q := "select household_id from containers where id = ? and deleted_at is null"
+		err = s.DB.QueryRow(q, photo.OwnerID).Scan(&householdID)
+	case OwnerItem:
+		q := `
+			select c.household_id from container_items i
+			inner join containers c on c.id = i.container_id
+			where i.id = ? and i.deleted_at is null and c.deleted_at is null
+		`
+		err = s.DB.QueryRow(q, photo.OwnerID).Scan(&householdID)
+	default:
+		err = ErrPhotoNotFound
+	}
+	if err == sql.ErrNoRows {
+		err = ErrPhotoNotFound
+	}
+	return householdID, err
+}
+
+// Open retrieves the image of a photo, or its thumbnail. The caller must close the reader.
+func (s *Store) Open(photo Photo, thumbnail bool) (io.ReadCloser, error) {
+	if thumbnail {
+		return s.Blobs.Get(photo.thumbnailKey())
+	}
+	return s.Blobs.Get(photo.key())
+}
+
+func (s *Store) deleteBlobs(photo Photo) error {
+	return blobs.DeleteAll(s.Blobs, photo.key(), photo.thumbnailKey())
+}
+
+// Delete removes a photo and its images.
+func (s *Store) Delete(photo Photo) error {
+	if _, err := s.DB.Exec("delete from photos where id = ?", photo.ID); err != nil {
+		return err
+	}
+	return s.deleteBlobs(photo)
+}
+
+// PurgeOrphans deletes the photos of containers and items that no longer exist.
+func (s *Store) PurgeOrphans() (int, error) {
+	q := `
+		select p.id, p.uuid
+		from photos p
+		left join containers c on p.owner_type = ? and c.id = p.owner_id
+		left join container_items i on p.owner_type = ? and i.id = p.owner_id
+		where c.id is null and i.id is null
+	`
+	keys := func(UUID string) []string {
+		photo := Photo{UUID: UUID}
+		return []string{photo.key(), photo.thumbnailKey()}
+	}
+	return blobs.PurgeOrphans(s.DB, s.Blobs, "photos", q, keys, OwnerContainer, OwnerItem)
+}
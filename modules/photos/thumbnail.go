@@ -0,0 +1,57 @@
+package photos
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales an image down to fit within size x size pixels, keeping its aspect ratio.
+// Every pixel of the thumbnail is the average of the pixels it covers, so detail is not lost to aliasing.
+// Transparent areas are flattened onto white. Images that already fit are copied as is.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dw, dh := sw, sh
+	if sw > size || sh > size {
+		if sw >= sh {
+			dw, dh = size, sh*size/sw
+		} else {
+			dw, dh = sw*size/sh, size
+		}
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	type sum struct{ r, g, b, a, n uint64 }
+	sums := make([]sum, dw*dh)
+	for sy := 0; sy < sh; sy++ {
+		row := sy * dh / sh * dw
+		for sx := 0; sx < sw; sx++ {
+			r, g, b, a := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+			s := &sums[row+sx*dw/sw]
+			s.r += uint64(r)
+			s.g += uint64(g)
+			s.b += uint64(b)
+			s.a += uint64(a)
+			s.n++
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for i, s := range sums {
+		if s.n == 0 {
+			continue
+		}
+		// Colors are premultiplied by alpha, so adding the missing alpha as white flattens the pixel.
+		white := 0xffff - s.a/s.n
+		dst.Set(i%dw, i/dw, color.RGBA64{
+			R: uint16(s.r/s.n + white),
+			G: uint16(s.g/s.n + white),
+			B: uint16(s.b/s.n + white),
+			A: 0xffff,
+		})
+	}
+	return dst
+}
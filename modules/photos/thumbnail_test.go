@@ -0,0 +1,47 @@
+package photos_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+)
+
+func TestThumbnail(t *testing.T) {
+	cases := []struct {
+		width, height int
+		want          image.Rectangle
+	}{
+		{1000, 500, image.Rect(0, 0, 100, 50)},
+		{300, 900, image.Rect(0, 0, 33, 100)},
+		{80, 60, image.Rect(0, 0, 80, 60)},
+		{5000, 10, image.Rect(0, 0, 100, 1)},
+	}
+	for _, c := range cases {
+		src := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+		if got := photos.Thumbnail(src, 100).Bounds(); got != c.want {
+			t.Errorf("%vx%v: expected %v, got %v", c.width, c.height, c.want, got)
+		}
+	}
+}
+
+func TestThumbnail_Averages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{200, 100, 50, 255})
+	got := photos.Thumbnail(src, 1).RGBAAt(0, 0)
+	want := color.RGBA{100, 50, 25, 255}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestThumbnail_FlattensTransparency(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	got := photos.Thumbnail(src, 2).RGBAAt(1, 1)
+	want := color.RGBA{255, 255, 255, 255}
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
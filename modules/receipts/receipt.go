@@ -0,0 +1,26 @@
+package receipts
+
+import (
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+)
+
+// Receipt is the proof of purchase of an item, kept as an image or a PDF.
+type Receipt struct {
+	ID          int64     `json:"-"`
+	UUID        string    `json:"uuid"`
+	ItemID      int64     `json:"-"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	Created     time.Time `json:"created"`
+}
+
+func (r *Receipt) setURL() {
+	r.URL = "/api/receipt/" + r.UUID
+}
+
+func (r Receipt) key() string {
+	return blobs.Key("receipts", r.UUID)
+}
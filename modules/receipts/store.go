@@ -0,0 +1,164 @@
+package receipts
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+var (
+	// ErrUnsupportedReceipt is returned for uploads that are not a JPEG, PNG or GIF image or a PDF.
+	ErrUnsupportedReceipt = errors.New("receipts must be JPEG, PNG or GIF images or PDF documents")
+	// ErrReceiptNotFound is returned when a receipt does not exist.
+	ErrReceiptNotFound = errors.New("receipt not found")
+)
+
+var supportedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+// Store persists receipts: their details in the database and their files in a blob store.
+type Store struct {
+	DB    *sql.DB
+	Blobs blobs.Store
+}
+
+// NewStore constructs a storage interface for receipts.
+// The blob store may be nil when only reading receipt details.
+func NewStore(db *sql.DB, blobStore blobs.Store) *Store {
+	return &Store{DB: db, Blobs: blobStore}
+}
+
+// Save stores an uploaded receipt for an item, replacing the receipt it had.
+// The type of the file is detected from its contents rather than trusted from the upload.
+func (s *Store) Save(itemID int64, data []byte) (Receipt, error) {
+	contentType := http.DetectContentType(data)
+	if !supportedTypes[contentType] {
+		return Receipt{}, ErrUnsupportedReceipt
+	}
+	previous, err := s.ForItems([]int64{itemID})
+	if err != nil {
+		return Receipt{}, err
+	}
+	receipt := Receipt{ItemID: itemID, ContentType: contentType, Size: int64(len(data))}
+	if receipt.UUID, err = blobs.NewUUID(); err != nil {
+		return receipt, err
+	}
+	if err = s.Blobs.Put(receipt.key(), receipt.ContentType, data); err != nil {
+		return receipt, err
+	}
+	q := `
+		insert into receipts (uuid, item_id, content_type, size, created) values (?, ?, ?, ?, now())
+		on duplicate key update uuid = values(uuid), content_type = values(content_type), size = values(size), created = now()
+	`
+	if _, err = s.DB.Exec(q, receipt.UUID, receipt.ItemID, receipt.ContentType, receipt.Size); err != nil {
+		s.Blobs.Delete(receipt.key())
+		return receipt, err
+	}
+	if old, ok := previous[itemID]; ok {
+		s.Blobs.Delete(old.key())
+	}
+	return s.ByUUID(receipt.UUID)
+}
+
+const receiptFields = "id, uuid, item_id, content_type, size, created"
+
+func scanReceipt(row models.Scanner) (Receipt, error) {
+	var receipt Receipt
+	err := row.Scan(
+		&receipt.ID,
+		&receipt.UUID,
+		&receipt.ItemID,
+		&receipt.ContentType,
+		&receipt.Size,
+		&receipt.Created)
+	receipt.setURL()
+	return receipt, err
+}
+
+// ByUUID retrieves a receipt.
+func (s *Store) ByUUID(UUID string) (Receipt, error) {
+	receipt, err := scanReceipt(s.DB.QueryRow("select "+receiptFields+" from receipts where uuid = ?", UUID))
+	if err == sql.ErrNoRows {
+		err = ErrReceiptNotFound
+	}
+	return receipt, err
+}
+
+// ForItems retrieves the receipts of many items at once, keyed by item ID.
+// Items without a receipt are left out.
+func (s *Store) ForItems(itemIDs []int64) (map[int64]Receipt, error) {
+	byItem := make(map[int64]Receipt)
+	if len(itemIDs) == 0 {
+		return byItem, nil
+	}
+	args := make([]interface{}, len(itemIDs))
+	for i, ID := range itemIDs {
+		args[i] = ID
+	}
+	q := "select " + receiptFields + " from receipts where item_id in (?" + strings.Repeat(",?", len(itemIDs)-1) + ")"
+	rows, err := s.DB.Query(q, args...)
+	if err != nil {
+		return byItem, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		receipt, err := scanReceipt(rows)
+		if err != nil {
+			return byItem, err
+		}
+		byItem[receipt.ItemID] = receipt
+	}
+	return byItem, rows.Err()
+}
+
+// HouseholdID resolves the household of the item a receipt belongs to.
+// Receipts of items in the trash are treated as missing.
+func (s *Store) HouseholdID(receipt Receipt) (int64, error) {
+	q := `
+		select c.household_id from container_items i
+		inner join containers c on c.id = i.container_id
+		where i.id = ? and i.deleted_at is null and c.deleted_at is null
+	`
+	var householdID int64
+	err := s.DB.QueryRow(q, receipt.ItemID).Scan(&householdID)
+	if err == sql.ErrNoRows {
+		err = ErrReceiptNotFound
+	}
+	return householdID, err
+}
+
+// Open retrieves the file of a receipt. The caller must close the reader.
+func (s *Store) Open(receipt Receipt) (io.ReadCloser, error) {
+	return s.Blobs.Get(receipt.key())
+}
+
+// Delete removes a receipt and its file.
+func (s *Store) Delete(receipt Receipt) error {
+	if _, err := s.DB.Exec("delete from receipts where id = ?", receipt.ID); err != nil {
+		return err
+	}
+	return s.Blobs.Delete(receipt.key())
+}
+
+// PurgeOrphans deletes the receipts of items that no longer exist.
+func (s *Store) PurgeOrphans() (int, error) {
+	q := `
+		select r.id, r.uuid
+		from receipts r
+		left join container_items i on i.id = r.item_id
+		where i.id is null
+	`
+	keys := func(UUID string) []string {
+		return []string{Receipt{UUID: UUID}.key()}
+	}
+	return blobs.PurgeOrphans(s.DB, s.Blobs, "receipts", q, keys)
+}
@@ -0,0 +1,311 @@
+package reports
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	"github.com/cjsaylor/boxmeup-go/modules/receipts"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+// InventoryItem is a line of the inventory report.
+// Prices are per unit, while the value of the location includes the quantity.
+type InventoryItem struct {
+	ItemID           int64             `json:"item_id"`
+	Body             string            `json:"body"`
+	Quantity         int               `json:"quantity"`
+	ContainerID      int64             `json:"container_id"`
+	Container        string            `json:"container"`
+	Barcode          string            `json:"barcode,omitempty"`
+	Currency         string            `json:"currency,omitempty"`
+	PurchasePrice    *valuation.Amount `json:"purchase_price,omitempty"`
+	PurchaseDate     *valuation.Date   `json:"purchase_date,omitempty"`
+	ReplacementValue *valuation.Amount `json:"replacement_value,omitempty"`
+	ReceiptURL       string            `json:"receipt_url,omitempty"`
+}
+
+// InventoryLocation lists the items in the containers at a location.
+// Containers without a location are listed under a location ID of 0.
+type InventoryLocation struct {
+	LocationID int64            `json:"location_id"`
+	Location   string           `json:"location"`
+	Items      []InventoryItem  `json:"items"`
+	Value      valuation.Totals `json:"value"`
+}
+
+// Inventory is a valued list of everything a household keeps, grouped by location, for filing an insurance claim.
+type Inventory struct {
+	HouseholdID   int64               `json:"household_id"`
+	Generated     time.Time           `json:"generated"`
+	Locations     []InventoryLocation `json:"locations"`
+	ItemCount     int                 `json:"item_count"`
+	UnvaluedCount int                 `json:"unvalued_count"`
+	Value         valuation.Totals    `json:"value"`
+}
+
+// NewInventory starts an empty inventory of a household.
+func NewInventory(householdID int64) *Inventory {
+	return &Inventory{
+		HouseholdID: householdID,
+		Generated:   time.Now().UTC(),
+		Locations:   []InventoryLocation{},
+		Value:       valuation.Totals{},
+	}
+}
+
+// Add lists an item under its location and includes it in the totals.
+func (i *Inventory) Add(locationID int64, location string, item InventoryItem) {
+	index := -1
+	for n := range i.Locations {
+		if i.Locations[n].LocationID == locationID {
+			index = n
+			break
+		}
+	}
+	if index < 0 {
+		i.Locations = append(i.Locations, InventoryLocation{
+			LocationID: locationID,
+			Location:   location,
+			Items:      []InventoryItem{},
+			Value:      valuation.Totals{},
+		})
+		index = len(i.Locations) - 1
+	}
+	section := &i.Locations[index]
+	section.Items = append(section.Items, item)
+	i.ItemCount++
+	if item.Currency == "" {
+		i.UnvaluedCount++
+		return
+	}
+	var purchasePrice, replacementValue valuation.Amount
+	if item.PurchasePrice != nil {
+		purchasePrice = item.PurchasePrice.Times(item.Quantity)
+	}
+	if item.ReplacementValue != nil {
+		replacementValue = item.ReplacementValue.Times(item.Quantity)
+	}
+	section.Value = section.Value.Add(item.Currency, purchasePrice, replacementValue)
+	i.Value = i.Value.Add(item.Currency, purchasePrice, replacementValue)
+}
+
+// Sort orders the locations by name, leaving containers without a location last,
+// and the items of each location by container and name.
+func (i *Inventory) Sort() {
+	sort.SliceStable(i.Locations, func(a, b int) bool {
+		if (i.Locations[a].LocationID == 0) != (i.Locations[b].LocationID == 0) {
+			return i.Locations[b].LocationID == 0
+		}
+		return i.Locations[a].Location < i.Locations[b].Location
+	})
+	for _, section := range i.Locations {
+		items := section.Items
+		sort.SliceStable(items, func(a, b int) bool {
+			if items[a].Container != items[b].Container {
+				return items[a].Container < items[b].Container
+			}
+			return items[a].Body < items[b].Body
+		})
+	}
+}
+
+func formatAmount(amount *valuation.Amount) string {
+	if amount == nil {
+		return ""
+	}
+	return amount.String()
+}
+
+// WriteCSV writes the inventory as a spreadsheet with a line for each item,
+// followed by a subtotal for each location and a total for each currency.
+func (i *Inventory) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"Location", "Container", "Item", "Quantity", "Barcode", "Purchase date", "Currency",
+		"Unit purchase price", "Unit replacement value", "Purchase price", "Replacement value", "Receipt",
+	})
+	totalLines := func(label string, location string, totals valuation.Totals) {
+		for _, total := range totals {
+			out.Write([]string{
+				location, "", label, "", "", "", total.Currency,
+				"", "", total.PurchasePrice.String(), total.ReplacementValue.String(), "",
+			})
+		}
+	}
+	for _, section := range i.Locations {
+		location := section.Location
+		if section.LocationID == 0 {
+			location = "(no location)"
+		}
+		for _, item := range section.Items {
+			var purchaseDate, purchasePrice, replacementValue string
+			if item.PurchaseDate != nil {
+				purchaseDate = item.PurchaseDate.String()
+			}
+			if item.PurchasePrice != nil {
+				purchasePrice = item.PurchasePrice.Times(item.Quantity).String()
+			}
+			if item.ReplacementValue != nil {
+				replacementValue = item.ReplacementValue.Times(item.Quantity).String()
+			}
+			out.Write([]string{
+				location, item.Container, item.Body, strconv.Itoa(item.Quantity), item.Barcode, purchaseDate, item.Currency,
+				formatAmount(item.PurchasePrice), formatAmount(item.ReplacementValue), purchasePrice, replacementValue,
+				item.ReceiptURL,
+			})
+		}
+		totalLines("Subtotal", location, section.Value)
+	}
+	totalLines("Total", "", i.Value)
+	out.Flush()
+	return out.Error()
+}
+
+// BuildInventory lists every item in the live containers of a household with its value.
+func BuildInventory(db *sql.DB, householdID int64) (*Inventory, error) {
+	names, err := locationNames(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	paths, containerLocations, err := containerPaths(db, householdID)
+	if err != nil {
+		return nil, err
+	}
+	q := `
+		select ci.id, ci.container_id, ci.body, ci.quantity, ci.barcode, ci.currency,
+			ci.purchase_price, ci.replacement_value, ci.purchase_date
+		from container_items ci
+		inner join containers c on c.id = ci.container_id
+		where c.household_id = ? and ci.deleted_at is null and c.deleted_at is null
+	`
+	rows, err := db.Query(q, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []InventoryItem
+	var itemIDs []int64
+	for rows.Next() {
+		var item InventoryItem
+		err = rows.Scan(
+			&item.ItemID,
+			&item.ContainerID,
+			&item.Body,
+			&item.Quantity,
+			&item.Barcode,
+			&item.Currency,
+			&item.PurchasePrice,
+			&item.ReplacementValue,
+			&item.PurchaseDate)
+		if err != nil {
+			return nil, err
+		}
+		item.Container = paths[item.ContainerID]
+		list = append(list, item)
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	received, err := receipts.NewStore(db, nil).ForItems(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	inventory := NewInventory(householdID)
+	for _, item := range list {
+		if receipt, ok := received[item.ItemID]; ok {
+			item.ReceiptURL = receipt.URL
+		}
+		locationID := containerLocations[item.ContainerID]
+		if _, ok := names[locationID]; !ok {
+			locationID = 0
+		}
+		inventory.Add(locationID, names[locationID], item)
+	}
+	inventory.Sort()
+	return inventory, nil
+}
+
+// locationNames names the live locations of a household with their ancestors, e.g. "Home > Garage".
+func locationNames(db *sql.DB, householdID int64) (map[int64]string, error) {
+	rows, err := db.Query("select id, name, path from locations where household_id = ? and deleted_at is null", householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := make(map[int64]string)
+	paths := make(map[int64]string)
+	for rows.Next() {
+		var ID int64
+		var name, path string
+		if err = rows.Scan(&ID, &name, &path); err != nil {
+			return nil, err
+		}
+		names[ID] = name
+		paths[ID] = path
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	full := make(map[int64]string, len(names))
+	for ID, path := range paths {
+		var crumbs []string
+		for _, ancestorID := range append(locations.AncestorIDs(path), ID) {
+			crumbs = append(crumbs, names[ancestorID])
+		}
+		full[ID] = strings.Join(crumbs, " > ")
+	}
+	return full, nil
+}
+
+// containerPaths names the live containers of a household with the containers they are nested in, e.g. "Tote > Bin",
+// and resolves the location of each.
+func containerPaths(db *sql.DB, householdID int64) (map[int64]string, map[int64]int64, error) {
+	q := "select id, parent_id, location_id, name from containers where household_id = ? and deleted_at is null"
+	rows, err := db.Query(q, householdID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	type node struct {
+		parentID   int64
+		locationID int64
+		name       string
+	}
+	nodes := make(map[int64]node)
+	for rows.Next() {
+		var ID int64
+		var n node
+		if err = rows.Scan(&ID, &n.parentID, &n.locationID, &n.name); err != nil {
+			return nil, nil, err
+		}
+		nodes[ID] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	paths := make(map[int64]string, len(nodes))
+	locationIDs := make(map[int64]int64, len(nodes))
+	for ID, n := range nodes {
+		path := containers.Path{{Type: "container", ID: ID, Name: n.name}}
+		parentID := n.parentID
+		for depth := 0; parentID > 0 && depth < containers.MaxDepth; depth++ {
+			parent, ok := nodes[parentID]
+			if !ok {
+				break
+			}
+			path = append(containers.Path{{Type: "container", ID: parentID, Name: parent.name}}, path...)
+			parentID = parent.parentID
+		}
+		paths[ID] = path.String()
+		locationIDs[ID] = n.locationID
+	}
+	return paths, locationIDs, nil
+}
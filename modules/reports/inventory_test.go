@@ -0,0 +1,86 @@
+package reports_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/reports"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+func amount(value valuation.Amount) *valuation.Amount {
+	return &value
+}
+
+func testInventory() *reports.Inventory {
+	inventory := reports.NewInventory(1)
+	inventory.Add(0, "", reports.InventoryItem{ItemID: 1, Body: "Tent", Quantity: 1, Container: "Camping"})
+	inventory.Add(4, "Home > Garage", reports.InventoryItem{
+		ItemID: 2, Body: "Drill", Quantity: 1, Container: "Tools", Currency: "USD",
+		PurchasePrice: amount(8999), ReplacementValue: amount(12000),
+	})
+	inventory.Add(2, "Home > Attic", reports.InventoryItem{
+		ItemID: 3, Body: "Ornaments", Quantity: 3, Container: "Tote > Bin", Currency: "USD",
+		PurchasePrice: amount(1050),
+	})
+	inventory.Add(4, "Home > Garage", reports.InventoryItem{
+		ItemID: 4, Body: "Bike", Quantity: 1, Container: "", Currency: "EUR",
+		ReplacementValue: amount(40000),
+	})
+	inventory.Sort()
+	return inventory
+}
+
+func TestInventory_Add(t *testing.T) {
+	inventory := testInventory()
+	var order []string
+	for _, section := range inventory.Locations {
+		order = append(order, section.Location)
+	}
+	if strings.Join(order, "|") != "Home > Attic|Home > Garage|" {
+		t.Errorf("Unexpected location order %v", order)
+	}
+	if inventory.ItemCount != 4 || inventory.UnvaluedCount != 1 {
+		t.Errorf("Expected 4 items with 1 unvalued but got %v and %v", inventory.ItemCount, inventory.UnvaluedCount)
+	}
+	attic := inventory.Locations[0]
+	if len(attic.Value) != 1 || attic.Value[0].PurchasePrice != 3150 {
+		t.Errorf("Expected the attic to total 31.50 for three ornaments but got %v", attic.Value)
+	}
+	garage := inventory.Locations[1]
+	if garage.Items[0].Body != "Bike" || garage.Items[1].Body != "Drill" {
+		t.Errorf("Expected garage items to be ordered by container but got %v", garage.Items)
+	}
+	expected := valuation.Totals{
+		{Currency: "EUR", PurchasePrice: 0, ReplacementValue: 40000},
+		{Currency: "USD", PurchasePrice: 12149, ReplacementValue: 12000},
+	}
+	if len(inventory.Value) != 2 || inventory.Value[0] != expected[0] || inventory.Value[1] != expected[1] {
+		t.Errorf("Expected %v but got %v", expected, inventory.Value)
+	}
+}
+
+func TestInventory_WriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := testInventory().WriteCSV(&out); err != nil {
+		t.Error(err)
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"Location,Container,Item,Quantity,Barcode,Purchase date,Currency,Unit purchase price,Unit replacement value,Purchase price,Replacement value,Receipt",
+		"Home > Attic,Tote > Bin,Ornaments,3,,,USD,10.50,,31.50,,",
+		"Home > Attic,,Subtotal,,,,USD,,,31.50,0.00,",
+		"Home > Garage,,Bike,1,,,EUR,,400.00,,400.00,",
+		"Home > Garage,Tools,Drill,1,,,USD,89.99,120.00,89.99,120.00,",
+		"Home > Garage,,Subtotal,,,,EUR,,,0.00,400.00,",
+		"Home > Garage,,Subtotal,,,,USD,,,89.99,120.00,",
+		"(no location),Camping,Tent,1,,,,,,,,",
+		",,Total,,,,EUR,,,0.00,400.00,",
+		",,Total,,,,USD,,,121.49,120.00,",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected CSV:\n%v", out.String())
+	}
+}
@@ -0,0 +1,153 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/admin"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// adminHandler restricts access to administrators. It must be chained after authHandler.
+// Admin status is checked against the database rather than trusting the token claim.
+func adminHandler(next http.Handler) http.Handler {
+	fn := func(res http.ResponseWriter, req *http.Request) {
+		var userKey userKey = "user"
+		userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+		db, _ := database.GetDBResource()
+		user, err := users.NewStore(db).ByID(userID)
+		db.Close()
+		if err != nil || !user.IsAdmin {
+			http.Error(res, "Administrator access required.", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(res, req)
+	}
+	return http.HandlerFunc(fn)
+}
+
+func writeAdminError(res http.ResponseWriter, jsonOut *json.Encoder, err error, failure string) {
+	switch err {
+	case users.ErrUserNotFound:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "User not found."})
+	case admin.ErrSelfModification:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to perform this action on yourself."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, failure})
+	}
+}
+
+// AdminUsersHandler lists and searches users with their usage counts.
+func AdminUsersHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	adminID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	params := req.URL.Query()
+	var limit models.QueryLimit
+	page, _ := strconv.Atoi(params.Get("page"))
+	limit.SetPage(page, admin.QueryLimit)
+	adminModel := admin.NewStore(db)
+	sort := adminModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")))
+	filter := admin.UserFilter{Term: params.Get("term")}
+	response, err := adminModel.FilteredUsers(adminID, filter, sort, limit)
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		writeAdminError(res, jsonOut, err, "Unable to retrieve users.")
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(response)
+}
+
+// AdminUserHandler gets a single user with their usage counts.
+func AdminUserHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	adminID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	userID, _ := strconv.Atoi(vars["id"])
+	summary, err := admin.NewStore(db).UserSummary(adminID, int64(userID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		writeAdminError(res, jsonOut, err, "Unable to retrieve user.")
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(summary)
+}
+
+// AdminDeactivateUserHandler deactivates a user and revokes their sessions.
+func AdminDeactivateUserHandler(res http.ResponseWriter, req *http.Request) {
+	setUserActive(res, req, false)
+}
+
+// AdminReactivateUserHandler reactivates a previously deactivated user.
+func AdminReactivateUserHandler(res http.ResponseWriter, req *http.Request) {
+	setUserActive(res, req, true)
+}
+
+func setUserActive(res http.ResponseWriter, req *http.Request, active bool) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	adminID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	userID, _ := strconv.Atoi(vars["id"])
+	err := admin.NewStore(db).SetActive(adminID, int64(userID), active)
+	if err != nil {
+		writeAdminError(res, json.NewEncoder(res), err, "Unable to update user.")
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// AdminForcePasswordResetHandler invalidates a user's password and mails them a reset link.
+func AdminForcePasswordResetHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	adminID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	userID, _ := strconv.Atoi(vars["id"])
+	user, err := users.NewStore(db).ByID(int64(userID))
+	if err == nil {
+		err = admin.NewStore(db).ForcePasswordReset(adminID, user.ID)
+	} else {
+		err = users.ErrUserNotFound
+	}
+	if err != nil {
+		writeAdminError(res, json.NewEncoder(res), err, "Unable to force a password reset.")
+		return
+	}
+	background(func() { sendPasswordReset(user.Email) })
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// AdminAuditLogHandler lists audit entries, optionally for a single target user.
+func AdminAuditLogHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	params := req.URL.Query()
+	var limit models.QueryLimit
+	page, _ := strconv.Atoi(params.Get("page"))
+	limit.SetPage(page, admin.QueryLimit)
+	userID, _ := strconv.Atoi(params.Get("user_id"))
+	response, err := admin.NewStore(db).AuditLog(int64(userID), limit)
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		writeAdminError(res, jsonOut, err, "Unable to retrieve audit log.")
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(response)
+}
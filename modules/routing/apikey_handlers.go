@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// apiKeyAuthScheme is the Authorization scheme for API key signed requests:
+//   Authorization: HMAC-SHA256 KeyId={api_key},Timestamp={unix},Nonce={nonce},Signature={hex}
+// See users.SignAPIRequest for how the signature is calculated.
+const apiKeyAuthScheme = "HMAC-SHA256"
+
+var errAPIRequestTooLarge = errors.New("Request body is too large.")
+
+// apiKeyClaims verifies a signed request and produces the same identity claims as a JWT login.
+// The body is read to check its signature, so it is limited to APIRequestMaxBytes.
+func apiKeyClaims(params string, res http.ResponseWriter, req *http.Request) (jwt.MapClaims, error) {
+	request := users.SignedRequest{
+		Method:     req.Method,
+		RequestURI: req.URL.RequestURI(),
+	}
+	for _, param := range strings.Split(params, ",") {
+		pair := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(pair) != 2 {
+			return nil, errors.New("Malformed signature parameters.")
+		}
+		switch pair[0] {
+		case "KeyId":
+			request.KeyID = pair[1]
+		case "Timestamp":
+			request.Timestamp = pair[1]
+		case "Nonce":
+			request.Nonce = pair[1]
+		case "Signature":
+			request.Signature = pair[1]
+		}
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, config.Config.APIRequestMaxBytes))
+		if _, ok := err.(*http.MaxBytesError); ok {
+			return nil, errAPIRequestTooLarge
+		} else if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		request.Body = body
+	}
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	user, key, err := users.NewStore(db).VerifyAPIRequest(request, config.Config.APIRequestWindow)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.MapClaims{
+		"id":         float64(user.ID),
+		"uuid":       user.UUID,
+		"api_key_id": float64(key.ID),
+	}, nil
+}
+
+// isAPIKeyRequest determines if the request was authenticated with an API key rather than a session.
+func isAPIKeyRequest(req *http.Request) bool {
+	var userKey userKey = "user"
+	_, ok := req.Context().Value(userKey).(jwt.MapClaims)["api_key_id"]
+	return ok
+}
+
+// CreateAPIKeyHandler generates a new API key for the user.
+// The secret key is only returned in this response.
+// Expected body:
+//   name
+func CreateAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	if isAPIKeyRequest(req) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "API keys can not be managed with an API key."})
+		return
+	}
+	key, err := users.NewStore(db).CreateAPIKey(userID, req.PostFormValue("name"))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to create API key."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(key)
+}
+
+// APIKeysHandler lists the user's API keys.
+func APIKeysHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	keys, err := users.NewStore(db).APIKeys(userID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-1, "Unable to retrieve API keys."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]users.APIKey{
+		"api_keys": keys,
+	})
+}
+
+// RevokeAPIKeyHandler deactivates one of the user's API keys.
+func RevokeAPIKeyHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	if isAPIKeyRequest(req) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "API keys can not be managed with an API key."})
+		return
+	}
+	keyID, _ := strconv.Atoi(vars["id"])
+	err := users.NewStore(db).RevokeAPIKey(userID, int64(keyID))
+	if err == users.ErrAPIKeyNotFound {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "API key not found."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to revoke API key."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
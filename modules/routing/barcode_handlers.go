@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cjsaylor/boxmeup-go/modules/barcodes"
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	productCatalogOnce sync.Once
+	productCatalog     barcodes.Provider
+)
+
+// productProvider is the source of product information for barcodes.
+// The catalog is read once, so changes to it take effect on restart.
+func productProvider() barcodes.Provider {
+	productCatalogOnce.Do(func() {
+		productCatalog = barcodes.NewCatalog(nil)
+		if config.Config.ProductCatalog == "" {
+			return
+		}
+		catalog, err := barcodes.LoadCatalog(config.Config.ProductCatalog)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		productCatalog = catalog
+	})
+	return productCatalog
+}
+
+// applyItemBarcode sets the barcode of an item from the barcode and barcode_type of a request.
+// An empty barcode removes it. New items without a body are named after the product of their barcode.
+func applyItemBarcode(res http.ResponseWriter, req *http.Request, item *items.ContainerItem) bool {
+	jsonOut := json.NewEncoder(res)
+	if _, ok := req.PostForm["barcode"]; ok {
+		item.Barcode, item.BarcodeType = "", ""
+		if value := req.PostFormValue("barcode"); value != "" {
+			barcode, err := barcodes.New(barcodes.Type(req.PostFormValue("barcode_type")), value)
+			if err != nil {
+				res.WriteHeader(http.StatusBadRequest)
+				jsonOut.Encode(jsonErrorResponse{-6, err.Error()})
+				return false
+			}
+			item.Barcode, item.BarcodeType = barcode.Value, barcode.Type
+		}
+	}
+	if item.ID != 0 || item.Body != "" || item.Barcode == "" {
+		return true
+	}
+	product, err := productProvider().Lookup(item.Barcode)
+	if err == barcodes.ErrProductNotFound {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-7, "No product is known for this barcode, a body is required."})
+		return false
+	} else if err != nil {
+		res.WriteHeader(http.StatusBadGateway)
+		jsonOut.Encode(jsonErrorResponse{-7, "Unable to look up the product of this barcode."})
+		return false
+	}
+	item.Body = product.Name
+	if name := []rune(product.Name); len(name) > items.MaxBodyLength {
+		item.Body = strings.TrimSpace(string(name[:items.MaxBodyLength]))
+	}
+	return true
+}
+
+// BarcodeHandler finds the items with a barcode in every household of the user, with their container and location.
+// The product of the barcode is included when it is known, to offer creating an item from it.
+// Expected query:
+//   barcode
+func BarcodeHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	value := req.URL.Query().Get("barcode")
+	if value == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-1, "Must provide a barcode."})
+		return
+	}
+	matches, err := items.NewStore(db).ByBarcode(userID, value)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve items."})
+		return
+	}
+	var product *barcodes.Product
+	if found, err := productProvider().Lookup(value); err == nil {
+		product = &found
+	} else if err != barcodes.ErrProductNotFound {
+		log.Println(err)
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(struct {
+		Items   []items.BarcodeMatch `json:"items"`
+		Product *barcodes.Product    `json:"product"`
+	}{matches, product})
+}
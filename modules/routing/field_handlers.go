@@ -0,0 +1,188 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// formFieldValues collects the custom field values of a request, sent as field[<id>]=value.
+func formFieldValues(form url.Values) map[int64]string {
+	raw := make(map[int64]string)
+	for key, values := range form {
+		if !strings.HasPrefix(key, "field[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		fieldID, err := strconv.ParseInt(key[len("field["):len(key)-1], 10, 64)
+		if err == nil && len(values) > 0 {
+			raw[fieldID] = values[0]
+		}
+	}
+	return raw
+}
+
+// fieldFilters validates custom field values to search items by.
+// Every field must belong to a household the user can view.
+func fieldFilters(db *sql.DB, userID int64, form url.Values) (fields.Values, error) {
+	filters := fields.Values{}
+	fieldModel := fields.NewStore(db)
+	for fieldID, value := range formFieldValues(form) {
+		definition, err := fieldModel.ByID(fieldID)
+		if err == nil && !householdRole(db, userID, definition.HouseholdID).Allows(households.RoleViewer) {
+			err = fields.ErrFieldNotFound
+		}
+		if err == nil {
+			value, err = definition.Normalize(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, fields.Value{FieldID: fieldID, Name: definition.Name, Type: definition.Type, Value: value})
+	}
+	return filters, nil
+}
+
+func writeFieldError(res http.ResponseWriter, err error, code int) {
+	jsonOut := json.NewEncoder(res)
+	if _, ok := err.(fields.ValueError); ok {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+		return
+	}
+	switch err {
+	case fields.ErrInvalidName, fields.ErrInvalidType, fields.ErrInvalidOptions, fields.ErrFieldNotFound:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+	case fields.ErrFieldExists:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{code, "Unable to save the field."})
+	}
+}
+
+// FieldsHandler lists the custom item fields of a household.
+// Accepts household_id, defaulting to the personal household.
+func FieldsHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	householdID, err := queryHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to view this household."})
+		return
+	}
+	definitions, err := fields.NewStore(db).Definitions(householdID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve fields."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]fields.Definition{"fields": definitions})
+}
+
+// CreateFieldHandler adds a custom item field to a household.
+// Expected body:
+//   name
+//   type (text, number, date, boolean, enum or url)
+//   option (repeated for every choice of an enum field)
+//   household_id (optional, defaults to the personal household)
+func CreateFieldHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	householdID, err := targetHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to add fields to this household."})
+		return
+	}
+	fieldType := fields.Type(req.PostFormValue("type"))
+	definition, err := fields.NewStore(db).Create(householdID, req.PostFormValue("name"), fieldType, req.PostForm["option"])
+	if err != nil {
+		writeFieldError(res, err, -2)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(definition)
+}
+
+// editableField retrieves the field of the request if the user may change it.
+func editableField(res http.ResponseWriter, req *http.Request, fieldModel *fields.Store, userID int64) (fields.Definition, bool) {
+	jsonOut := json.NewEncoder(res)
+	fieldID, _ := strconv.Atoi(mux.Vars(req)["id"])
+	definition, err := fieldModel.ByID(int64(fieldID))
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Field not found."})
+		return definition, false
+	}
+	if !householdRole(fieldModel.DB, userID, definition.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to modify this field."})
+		return definition, false
+	}
+	return definition, true
+}
+
+// UpdateFieldHandler renames a custom field or changes the choices of an enum field.
+// Expected body:
+//   name (optional)
+//   option (optional, repeated for every choice of an enum field)
+func UpdateFieldHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	fieldModel := fields.NewStore(db)
+	definition, ok := editableField(res, req, fieldModel, userID)
+	if !ok {
+		return
+	}
+	if name := req.PostFormValue("name"); name != "" {
+		definition.Name = name
+	}
+	if options, ok := req.PostForm["option"]; ok {
+		definition.Options = options
+	}
+	if err := fieldModel.Update(definition); err != nil {
+		writeFieldError(res, err, -3)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteFieldHandler removes a custom field from the household and from every item.
+func DeleteFieldHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	fieldModel := fields.NewStore(db)
+	definition, ok := editableField(res, req, fieldModel, userID)
+	if !ok {
+		return
+	}
+	if err := fieldModel.Delete(definition.ID); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to remove the field."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
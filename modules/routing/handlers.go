@@ -1,17 +1,23 @@
 package routing
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/cjsaylor/boxmeup-go/modules/config"
 	"github.com/cjsaylor/boxmeup-go/modules/containers"
 	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/fields"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
 	"github.com/cjsaylor/boxmeup-go/modules/items"
 	"github.com/cjsaylor/boxmeup-go/modules/locations"
 	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
 	"github.com/cjsaylor/boxmeup-go/modules/users"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
@@ -23,53 +29,186 @@ type jsonErrorResponse struct {
 	Text string `json:"text"`
 }
 
+// background runs a task outside of the request. net/http only recovers panics in handlers,
+// so a panic here, such as database.GetDBResource failing to reach MySQL, is logged instead of ending the server.
+func background(task func()) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Println(err)
+			}
+		}()
+		task()
+	}()
+}
+
+// signingKeys are loaded once at startup when asymmetric signing is configured.
+var signingKeys = loadSigningKeys()
+
+func loadSigningKeys() *users.KeySet {
+	if config.Config.JWTSigningKey == "" {
+		return nil
+	}
+	keys, err := users.LoadKeySet(config.Config.JWTSigningKey, config.Config.JWTVerificationKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return keys
+}
+
+func authConfig() users.AuthConfig {
+	return users.AuthConfig{
+		LegacySalt:      config.Config.LegacySalt,
+		JWTSecret:       config.Config.JWTSecret,
+		AccessTokenTTL:  config.Config.AccessTokenTTL,
+		RefreshTokenTTL: config.Config.RefreshTokenTTL,
+		Keys:            signingKeys,
+	}
+}
+
+// JWKSHandler publishes the public keys access tokens are signed with.
+func JWKSHandler(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Cache-Control", "public, max-age=300")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(signingKeys.JWKS())
+}
+
 // IndexHandler serves the static page
 func IndexHandler(res http.ResponseWriter, req *http.Request) {
 	fmt.Fprint(res, "Welcome!")
 }
 
 // LoginHandler authenticates via email and password
+// Users with two factor authentication receive a challenge to complete at /api/user/login/2fa.
+// Repeated failures are throttled per account and per IP address with a 429 and Retry-After.
 func LoginHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
+	defer db.Close()
 
-	token, err := users.NewStore(db).Login(
-		users.AuthConfig{
-			LegacySalt: config.Config.LegacySalt,
-			JWTSecret:  config.Config.JWTSecret,
-		},
-		req.PostFormValue("email"),
-		req.PostFormValue("password"))
+	email := req.PostFormValue("email")
+	limiter := newLoginThrottle(db, req, email)
+	if wait := limiter.retryAfter(); wait > 0 {
+		writeThrottled(res, -2, wait)
+		return
+	}
+	userModel := users.NewStore(db)
+	tokens, err := userModel.Login(authConfig(), email, req.PostFormValue("password"))
 	jsonOut := json.NewEncoder(res)
-	if err != nil {
+	if challenge, ok := err.(*users.TwoFactorChallenge); ok {
+		limiter.success()
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(twoFactorChallengeResponse{true, challenge})
+	} else if statusErr, ok := accountStatusErrors[err]; ok {
+		limiter.success()
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(statusErr)
+	} else if err == sql.ErrNoRows || err == users.ErrPasswordMismatch {
+		lockedOut := limiter.failure()
+		if err == users.ErrPasswordMismatch {
+			if user, err := userModel.ByEmail(email); err == nil {
+				events := []string{users.SecurityEventLoginFailed}
+				if lockedOut {
+					events = append(events, users.SecurityEventAccountLocked)
+				}
+				recordSecurityEvents(userModel, user.ID, limiter.IP, events...)
+			}
+		}
 		res.WriteHeader(http.StatusUnauthorized)
 		jsonOut.Encode(jsonErrorResponse{-1, "Authentication failure."})
+	} else if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to log in."})
 	} else {
+		limiter.success()
 		res.WriteHeader(http.StatusOK)
-		jsonOut.Encode(map[string]string{
-			"token": token,
-		})
+		jsonOut.Encode(tokens)
 	}
 }
 
-// RegisterHandler creates new users.
+// RefreshHandler exchanges a refresh token for a new token pair.
+// Expected body:
+//   refresh_token
+func RefreshHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+
+	tokens, err := users.NewStore(db).Refresh(authConfig(), req.PostFormValue("refresh_token"))
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(tokens)
+	case users.ErrInvalidRefreshToken:
+		res.WriteHeader(http.StatusUnauthorized)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid refresh token."})
+	case users.ErrRefreshTokenReused:
+		res.WriteHeader(http.StatusUnauthorized)
+		jsonOut.Encode(jsonErrorResponse{-2, "Refresh token reuse detected. Session has been revoked."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to refresh session."})
+	}
+}
+
+// LogoutHandler revokes the session of the current access token.
+func LogoutHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	claims := req.Context().Value(userKey).(jwt.MapClaims)
+	err := users.NewStore(db).Logout(claims)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-1, "Unable to end session."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterHandler creates new users and sends them an email verification link.
+// Users registering from a household invite pass its invite_token; the invite is accepted
+// and the account is verified immediately as the invite was delivered to the same address.
 func RegisterHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
+	defer db.Close()
 
 	email := req.PostFormValue("email")
 	password := req.PostFormValue("password")
-	id, err := users.NewStore(db).Register(
-		users.AuthConfig{
-			LegacySalt: config.Config.LegacySalt,
-			JWTSecret:  config.Config.JWTSecret,
-		},
-		email,
-		password)
+	inviteToken := req.PostFormValue("invite_token")
 	jsonOut := json.NewEncoder(res)
+	if inviteToken != "" {
+		invite, err := households.NewStore(db).PendingInvite(config.Config.JWTSecret, inviteToken)
+		if err != nil || !strings.EqualFold(invite.Email, strings.TrimSpace(email)) {
+			res.WriteHeader(http.StatusBadRequest)
+			jsonOut.Encode(jsonErrorResponse{-2, "Invalid or expired invite."})
+			return
+		}
+	}
+	userModel := users.NewStore(db)
+	id, err := userModel.Register(authConfig(), email, password)
 	if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
 		jsonOut.Encode(jsonErrorResponse{-1, err.Error()})
 		return
 	}
+	user, err := userModel.ByID(id)
+	if err == nil && inviteToken != "" {
+		if err = acceptRegistrationInvite(db, inviteToken, user); err == nil {
+			res.WriteHeader(http.StatusOK)
+			jsonOut.Encode(map[string]int64{
+				"id": id,
+			})
+			return
+		}
+		log.Println(err)
+	}
+	if err == nil {
+		err = sendVerificationEmail(user)
+	}
+	if err != nil {
+		log.Println(err)
+	}
 	res.WriteHeader(http.StatusOK)
 	jsonOut.Encode(map[string]int64{
 		"id": id,
@@ -79,7 +218,9 @@ func RegisterHandler(res http.ResponseWriter, req *http.Request) {
 // CreateContainerHandler allows creation of a container from a POST method
 // Expected body:
 //   name
+//   household_id (optional, defaults to the personal household)
 //   location_id (optional)
+//   parent_id (optional, nests the container; it takes the location of its parent)
 func CreateContainerHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
 	defer db.Close()
@@ -92,7 +233,14 @@ func CreateContainerHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "User specified not found."})
 		return
 	}
+	householdID, err := targetHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-6, "Not allowed to add containers to this household."})
+		return
+	}
 	record := containers.NewRecord(&user)
+	record.SetHousehold(householdID)
 	record.Name = req.PostFormValue("name")
 	if userLocationID := req.PostFormValue("location_id"); userLocationID != "" {
 		locationID, _ := strconv.Atoi(userLocationID)
@@ -101,7 +249,7 @@ func CreateContainerHandler(res http.ResponseWriter, req *http.Request) {
 			res.WriteHeader(http.StatusNotFound)
 			jsonOut.Encode(jsonErrorResponse{-5, "Location not found."})
 			return
-		} else if location.User.ID != userID {
+		} else if location.HouseholdID != householdID {
 			res.WriteHeader(http.StatusForbidden)
 			jsonOut.Encode(jsonErrorResponse{-3, "Not allowed to attach supplied location to this container."})
 			return
@@ -110,8 +258,19 @@ func CreateContainerHandler(res http.ResponseWriter, req *http.Request) {
 	} else {
 		record.SetLocation(nil)
 	}
-	err = containers.NewStore(db).Create(&record)
+	containerModel := containers.NewStore(db)
+	parent, err := formParent(containerModel, req)
 	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-7, "Parent container not found."})
+		return
+	}
+	record.SetParent(parent)
+	err = containerModel.Create(&record)
+	if isNestingError(err) {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-8, err.Error()})
+	} else if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
 		jsonOut.Encode(jsonErrorResponse{-2, "Failed to create the container."})
 	} else {
@@ -126,6 +285,7 @@ func CreateContainerHandler(res http.ResponseWriter, req *http.Request) {
 // @todo consider a new endpoint for just location attachment/detachment and remove location editing here
 // -> PUT /api/container/<id>/location/<location_id>
 // -> DELETE /api/container/<id>/location
+// A parent_id of 0 moves a nested container to the top level; omitting it keeps the current parent.
 func UpdateContainerHandler(res http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	db, _ := database.GetDBResource()
@@ -141,7 +301,7 @@ func UpdateContainerHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
 		return
 	}
-	if container.User.ID != userID {
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this container."})
 		return
@@ -155,7 +315,7 @@ func UpdateContainerHandler(res http.ResponseWriter, req *http.Request) {
 			res.WriteHeader(http.StatusNotFound)
 			jsonOut.Encode(jsonErrorResponse{-5, "Location not found."})
 			return
-		} else if location.User.ID != userID {
+		} else if location.HouseholdID != container.HouseholdID {
 			res.WriteHeader(http.StatusForbidden)
 			jsonOut.Encode(jsonErrorResponse{-3, "Not allowed to attach supplied location to this container."})
 			return
@@ -164,8 +324,21 @@ func UpdateContainerHandler(res http.ResponseWriter, req *http.Request) {
 	} else {
 		record.SetLocation(nil)
 	}
+	if _, ok := req.PostForm["parent_id"]; ok {
+		parent, err := formParent(containerModel, req)
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			jsonOut.Encode(jsonErrorResponse{-6, "Parent container not found."})
+			return
+		}
+		record.SetParent(parent)
+	}
 	err = containerModel.Update(&record)
-	if err != nil {
+	if isNestingError(err) {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-7, err.Error()})
+		return
+	} else if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
 		return
@@ -189,12 +362,12 @@ func DeleteContainerHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
 		return
 	}
-	if container.User.ID != userID {
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this container."})
 		return
 	}
-	err = containerModel.Delete(int64(containerID))
+	err = containerModel.Delete(int64(containerID), userID)
 	if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-3, "Error deleting container."})
@@ -218,7 +391,7 @@ func ContainerHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
 		return
 	}
-	if container.User.ID != userID {
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleViewer) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view this container."})
 		return
@@ -227,7 +400,10 @@ func ContainerHandler(res http.ResponseWriter, req *http.Request) {
 	jsonOut.Encode(container)
 }
 
-// ContainersHandler gets all user containers
+// ContainersHandler gets all containers in the user's households
+// Accepts household_id to limit results to a single household.
+// Accepts within_location_id to include containers of nested locations.
+// Accepts any_tag and all_tag (repeatable) to require at least one or every one of the tags.
 func ContainersHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
 	defer db.Close()
@@ -247,12 +423,21 @@ func ContainersHandler(res http.ResponseWriter, req *http.Request) {
 	limit.SetPage(page, containers.QueryLimit)
 	containerModel := containers.NewStore(db)
 	sort := containerModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")))
+	householdID, _ := strconv.Atoi(params.Get("household_id"))
 	filter := containers.ContainerFilter{
-		User:        user,
-		LocationIDs: params["location_id"],
+		User:              user,
+		HouseholdID:       int64(householdID),
+		LocationIDs:       params["location_id"],
+		WithinLocationIDs: params["within_location_id"],
+		AnyTags:           params["any_tag"],
+		AllTags:           params["all_tag"],
 	}
 	response, err := containerModel.FilteredContainers(filter, sort, limit)
-	if err != nil {
+	if err == tags.ErrInvalidName {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, err.Error()})
+		return
+	} else if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
 		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve containers."})
 		return
@@ -262,17 +447,41 @@ func ContainersHandler(res http.ResponseWriter, req *http.Request) {
 }
 
 // ContainerQR will output a QR code png for a specific container.
+// The code links to the container by its UUID so labels don't reveal internal IDs.
 func ContainerQR(res http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	containerID, _ := strconv.Atoi(vars["id"])
+	container, err := containers.NewStore(db).ByID(int64(containerID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
+		return
+	}
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view this container."})
+		return
+	}
 	// @todo Figure out where this will direct to in the SPA.
-	qrBytes, _ := qrcode.Encode(fmt.Sprintf("%v/container/%v", config.Config.WebHost, vars["id"]), qrcode.Medium, 250)
+	qrBytes, _ := qrcode.Encode(fmt.Sprintf("%v/container/%v", config.Config.WebHost, container.UUID), qrcode.Medium, 250)
 	res.Write(qrBytes)
 }
 
 // SaveContainerItemHandler allows creation of a container from a POST method
 // Expected body:
-//   body
+//   body (optional for new items with a barcode of a known product, which are named after it)
 //   quantity
+//   barcode (optional, empty to remove it)
+//   barcode_type (optional, upc-a, ean-13, code128 or qr, detected when omitted)
+//   purchase_price, replacement_value (optional, per unit, empty to remove them)
+//   currency (optional, defaults to DEFAULT_CURRENCY for priced items)
+//   purchase_date (optional, YYYY-MM-DD)
+//   field[<id>] (optional, for each custom field to set, empty to remove it)
 func SaveContainerItemHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
 	defer db.Close()
@@ -287,7 +496,7 @@ func SaveContainerItemHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Failed to retrieve the container."})
 		return
 	}
-	if container.User.ID != userID {
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to modify this container."})
 		return
@@ -298,8 +507,10 @@ func SaveContainerItemHandler(res http.ResponseWriter, req *http.Request) {
 	if _, ok := vars["item_id"]; ok {
 		itemID, _ := strconv.Atoi(vars["item_id"])
 		item, err = itemModel.ByID(int64(itemID))
-		if err != nil {
+		if err != nil || item.Container.ID != container.ID {
+			res.WriteHeader(http.StatusNotFound)
 			jsonOut.Encode(jsonErrorResponse{-3, "Unable to retrieve item to modify."})
+			return
 		}
 	} else {
 		item = items.ContainerItem{
@@ -312,6 +523,14 @@ func SaveContainerItemHandler(res http.ResponseWriter, req *http.Request) {
 	if body := req.PostFormValue("body"); body != "" {
 		item.Body = body
 	}
+	if !applyItemBarcode(res, req, &item) || !applyItemValuation(res, req, &item) {
+		return
+	}
+	item.Fields, err = fields.NewStore(db).Validate(container.HouseholdID, formFieldValues(req.PostForm))
+	if err != nil {
+		writeFieldError(res, err, -5)
+		return
+	}
 	if _, ok := vars["item_id"]; ok {
 		itemID, _ := strconv.Atoi(vars["item_id"])
 		item.ID = int64(itemID)
@@ -346,12 +565,12 @@ func DeleteContainerItemHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Item not found."})
 		return
 	}
-	if item.Container.User.ID != userID {
+	if !householdRole(db, userID, item.Container.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to delete this item."})
 		return
 	}
-	err = itemModel.Delete(item)
+	err = itemModel.Delete(item, userID)
 	if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-3, "Unable to delete this item."})
@@ -376,7 +595,7 @@ func ContainerItemsHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
 		return
 	}
-	if container.User.ID != userID {
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleViewer) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view items in this container."})
 		return
@@ -386,7 +605,7 @@ func ContainerItemsHandler(res http.ResponseWriter, req *http.Request) {
 	page, _ := strconv.Atoi(params.Get("page"))
 	limit.SetPage(page, containers.QueryLimit)
 	itemModel := items.NewStore(db)
-	sort := itemModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")))
+	sort := itemModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")), userID)
 	response, err := itemModel.GetContainerItems(&container, sort, limit)
 	if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
@@ -406,7 +625,12 @@ func SearchItemHandler(res http.ResponseWriter, req *http.Request) {
 	var limit models.QueryLimit
 	term := params.Get("term")
 	jsonOut := json.NewEncoder(res)
-	if term == "" {
+	filters, err := fieldFilters(db, userID, params)
+	if err != nil {
+		writeFieldError(res, err, -3)
+		return
+	}
+	if term == "" && len(filters) == 0 {
 		res.WriteHeader(http.StatusBadRequest)
 		jsonOut.Encode(jsonErrorResponse{-1, "Must provide a search term."})
 		return
@@ -414,8 +638,8 @@ func SearchItemHandler(res http.ResponseWriter, req *http.Request) {
 	page, _ := strconv.Atoi(params.Get("page"))
 	limit.SetPage(page, containers.QueryLimit)
 	itemModel := items.NewStore(db)
-	sort := itemModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")))
-	response, err := itemModel.SearchItems(int64(userID), term, sort, limit)
+	sort := itemModel.GetSortBy(params.Get("sort_field"), models.SortType(params.Get("sort_dir")), userID)
+	response, err := itemModel.SearchItems(int64(userID), term, filters, sort, limit)
 	if err != nil {
 		res.WriteHeader(http.StatusBadRequest)
 		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve items."})
@@ -429,6 +653,8 @@ func SearchItemHandler(res http.ResponseWriter, req *http.Request) {
 // Expected body:
 //   - name
 //   - address
+//   - household_id (optional, defaults to the personal household)
+//   - parent_id (optional, a location of the same household to nest it in)
 func CreateLocationHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
 	defer db.Close()
@@ -441,13 +667,23 @@ func CreateLocationHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Unable to find user to associate this location."})
 		return
 	}
+	householdID, err := targetHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-3, "Not allowed to add locations to this household."})
+		return
+	}
 	location := locations.Location{
-		User:    user,
-		Name:    req.PostFormValue("name"),
-		Address: req.PostFormValue("address"),
+		User:        user,
+		HouseholdID: householdID,
+		Name:        req.PostFormValue("name"),
+		Address:     req.PostFormValue("address"),
 	}
+	location.ParentID, _ = strconv.ParseInt(req.PostFormValue("parent_id"), 10, 64)
 	err = locations.NewStore(db).Create(&location)
-	if err != nil {
+	if writeLocationParentError(res, err, -4) {
+		return
+	} else if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-2, "Unable to store location."})
 		return
@@ -459,6 +695,7 @@ func CreateLocationHandler(res http.ResponseWriter, req *http.Request) {
 }
 
 // UpdateLocationHandler will handle updating location based on user input
+// A parent_id of 0 moves a nested location to the top level; omitting it keeps the current parent.
 func UpdateLocationHandler(res http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	db, _ := database.GetDBResource()
@@ -474,15 +711,20 @@ func UpdateLocationHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Location not found."})
 		return
 	}
-	if userID != location.User.ID {
+	if !householdRole(db, userID, location.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to modify this location."})
 		return
 	}
 	location.Name = req.PostFormValue("name")
 	location.Address = req.PostFormValue("address")
+	if _, ok := req.PostForm["parent_id"]; ok {
+		location.ParentID, _ = strconv.ParseInt(req.PostFormValue("parent_id"), 10, 64)
+	}
 	err = locationModel.Update(&location)
-	if err != nil {
+	if writeLocationParentError(res, err, -4) {
+		return
+	} else if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-3, "Failed to update location."})
 		return
@@ -491,6 +733,8 @@ func UpdateLocationHandler(res http.ResponseWriter, req *http.Request) {
 }
 
 // DeleteLocationHandler will remove a location upon user request.
+// The policy decides what happens to the containers at the location: refuse (default), unassign or
+// reassign to the location of reassign_to_id. Responds with what happened to the containers.
 func DeleteLocationHandler(res http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	db, _ := database.GetDBResource()
@@ -506,20 +750,40 @@ func DeleteLocationHandler(res http.ResponseWriter, req *http.Request) {
 		jsonOut.Encode(jsonErrorResponse{-1, "Location not found."})
 		return
 	}
-	if userID != location.User.ID {
+	if !householdRole(db, userID, location.HouseholdID).Allows(households.RoleEditor) {
 		res.WriteHeader(http.StatusForbidden)
 		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to remove this location."})
 		return
 	}
-	err = locationModel.Delete(int64(locationID))
+	policy, err := locations.ParseDeletePolicy(req.FormValue("policy"))
 	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
+		return
+	}
+	reassignToID, _ := strconv.ParseInt(req.FormValue("reassign_to_id"), 10, 64)
+	result, err := locationModel.Delete(int64(locationID), userID, policy, reassignToID)
+	switch err {
+	case nil:
+		jsonOut.Encode(result)
+	case locations.ErrLocationInUse:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{
+			-5,
+			fmt.Sprintf("Location still has %v containers. Unassign or reassign them to remove it.", result.ContainerCount),
+		})
+	case locations.ErrReassignTarget:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-6, err.Error()})
+	default:
+		log.Println(err)
 		res.WriteHeader(http.StatusInternalServerError)
 		jsonOut.Encode(jsonErrorResponse{-3, "Unable to remove location."})
 	}
-	res.WriteHeader(http.StatusNoContent)
 }
 
-// LocationsHandler will retrieve user locations
+// LocationsHandler will retrieve locations in the user's households
+// Accepts household_id to limit results to a single household.
 func LocationsHandler(res http.ResponseWriter, req *http.Request) {
 	db, _ := database.GetDBResource()
 	defer db.Close()
@@ -548,8 +812,10 @@ func LocationsHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 	sort := locationModel.GetSortBy(sortField, models.SortType(params.Get("sort_dir")))
+	householdID, _ := strconv.Atoi(params.Get("household_id"))
 	filter := locations.LocationFilter{
-		User: user,
+		User:                  user,
+		HouseholdID:           int64(householdID),
 		IsAttachedToContainer: params.Get("is_attached_to_container") == "T",
 	}
 	response, err := locationModel.FilteredLocations(filter, sort, limit)
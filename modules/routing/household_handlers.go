@@ -0,0 +1,214 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// householdRole resolves the role of the user in a household. Non-members have no role.
+func householdRole(db *sql.DB, userID int64, householdID int64) households.Role {
+	role, _ := households.NewStore(db).Role(householdID, userID)
+	return role
+}
+
+// targetHousehold resolves the household new records are created in.
+// Without a household_id the user's personal household is used.
+func targetHousehold(db *sql.DB, userID int64, req *http.Request) (int64, error) {
+	if householdID, err := strconv.Atoi(req.PostFormValue("household_id")); err == nil && householdID > 0 {
+		return int64(householdID), nil
+	}
+	household, err := households.NewStore(db).Personal(userID)
+	return household.ID, err
+}
+
+// queryHousehold is targetHousehold for requests that take household_id from the query string.
+func queryHousehold(db *sql.DB, userID int64, req *http.Request) (int64, error) {
+	if householdID, err := strconv.Atoi(req.URL.Query().Get("household_id")); err == nil && householdID > 0 {
+		return int64(householdID), nil
+	}
+	household, err := households.NewStore(db).Personal(userID)
+	return household.ID, err
+}
+
+type householdResponse struct {
+	households.Household
+	Members []households.Member `json:"members"`
+}
+
+// HouseholdsHandler lists the households of the current user along with their role.
+func HouseholdsHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdModel := households.NewStore(db)
+	jsonOut := json.NewEncoder(res)
+	// Ensures the personal household exists before listing.
+	_, err := householdModel.Personal(userID)
+	var list []households.Household
+	if err == nil {
+		list, err = householdModel.AllForUser(userID)
+	}
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-1, "Unable to retrieve households."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]households.Household{
+		"households": list,
+	})
+}
+
+// CreateHouseholdHandler creates a household owned by the current user.
+// Expected body:
+//   name
+func CreateHouseholdHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	household, err := households.NewStore(db).Create(userID, req.PostFormValue("name"))
+	jsonOut := json.NewEncoder(res)
+	if err == households.ErrEmptyName {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-1, "Household must have a name."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to create household."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(household)
+}
+
+// HouseholdHandler gets a household and its members.
+func HouseholdHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	householdModel := households.NewStore(db)
+	household, err := householdModel.ForUser(int64(householdID), userID)
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Household not found."})
+		return
+	}
+	members, err := householdModel.Members(household.ID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve household members."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(householdResponse{household, members})
+}
+
+// UpdateHouseholdHandler renames a household. Only owners may do so.
+// Expected body:
+//   name
+func UpdateHouseholdHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	jsonOut := json.NewEncoder(res)
+	if !householdRole(db, userID, int64(householdID)).Allows(households.RoleOwner) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to modify this household."})
+		return
+	}
+	err := households.NewStore(db).Rename(int64(householdID), req.PostFormValue("name"))
+	if err == households.ErrEmptyName {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-2, "Household must have a name."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to update household."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func writeMemberError(res http.ResponseWriter, jsonOut *json.Encoder, err error) {
+	switch err {
+	case households.ErrNotMember:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "Member not found."})
+	case households.ErrInvalidRole:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "Role must be one of owner, editor or viewer."})
+	case households.ErrLastOwner:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-4, "A household must have at least one owner."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-5, "Unable to update member."})
+	}
+}
+
+// UpdateHouseholdMemberHandler changes the role of a member. Only owners may do so.
+// Expected body:
+//   role (owner, editor or viewer)
+func UpdateHouseholdMemberHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	memberID, _ := strconv.Atoi(vars["user_id"])
+	jsonOut := json.NewEncoder(res)
+	if !householdRole(db, userID, int64(householdID)).Allows(households.RoleOwner) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to manage members of this household."})
+		return
+	}
+	role := households.Role(req.PostFormValue("role"))
+	err := households.NewStore(db).SetRole(int64(householdID), int64(memberID), role)
+	if err != nil {
+		writeMemberError(res, jsonOut, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveHouseholdMemberHandler removes a member from a household.
+// Owners may remove anyone; other members may only remove themselves.
+func RemoveHouseholdMemberHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	memberID, _ := strconv.Atoi(vars["user_id"])
+	jsonOut := json.NewEncoder(res)
+	role := householdRole(db, userID, int64(householdID))
+	if !role.Allows(households.RoleOwner) && !(role.Allows(households.RoleViewer) && int64(memberID) == userID) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to manage members of this household."})
+		return
+	}
+	err := households.NewStore(db).RemoveMember(int64(householdID), int64(memberID))
+	if err != nil {
+		writeMemberError(res, jsonOut, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
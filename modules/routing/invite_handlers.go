@@ -0,0 +1,237 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/mailer"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+func sendInvite(invite households.Invite, inviter users.User) error {
+	token, err := households.InviteToken(config.Config.JWTSecret, invite)
+	if err != nil {
+		return err
+	}
+	return Mailer.Send(mailer.Message{
+		To:      invite.Email,
+		Subject: fmt.Sprintf("You have been invited to %v on Boxmeup", invite.HouseholdName),
+		Body: fmt.Sprintf(
+			"%v invited you to join %v on Boxmeup as %v.\n\n"+
+				"Accept or decline the invitation (valid until %v):\n%v/invite?token=%v\n",
+			inviter.Email,
+			invite.HouseholdName,
+			invite.Role,
+			invite.Expires.Format("January 2, 2006"),
+			config.Config.WebHost,
+			url.QueryEscape(token)),
+	})
+}
+
+// acceptRegistrationInvite completes an invite for a user who registered from it.
+// Receiving the invite proves ownership of the email address, so the account is verified.
+func acceptRegistrationInvite(db *sql.DB, token string, user users.User) error {
+	_, err := households.NewStore(db).AcceptInvite(config.Config.JWTSecret, token, user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	return users.NewStore(db).MarkVerified(user.ID)
+}
+
+// CreateInviteHandler invites an email address to join a household. Only owners may invite.
+// Expected body:
+//   email
+//   role (owner, editor or viewer)
+func CreateInviteHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	jsonOut := json.NewEncoder(res)
+	if !householdRole(db, userID, int64(householdID)).Allows(households.RoleOwner) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to invite members to this household."})
+		return
+	}
+	invite, err := households.NewStore(db).CreateInvite(
+		int64(householdID),
+		userID,
+		req.PostFormValue("email"),
+		households.Role(req.PostFormValue("role")),
+		config.Config.InviteTTL)
+	switch err {
+	case nil:
+	case households.ErrInvalidInviteEmail:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invalid email address."})
+		return
+	case households.ErrInvalidRole:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "Role must be one of owner, editor or viewer."})
+		return
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-4, "Unable to create invite."})
+		return
+	}
+	inviter, err := users.NewStore(db).ByID(userID)
+	if err == nil {
+		err = sendInvite(invite, inviter)
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusBadGateway)
+		jsonOut.Encode(jsonErrorResponse{-5, "Invite created but could not be delivered."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(invite)
+}
+
+// InvitesHandler lists the pending invites of a household. Only owners may list them.
+func InvitesHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	jsonOut := json.NewEncoder(res)
+	if !householdRole(db, userID, int64(householdID)).Allows(households.RoleOwner) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to view invites of this household."})
+		return
+	}
+	invites, err := households.NewStore(db).PendingInvites(int64(householdID))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve invites."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]households.Invite{
+		"invites": invites,
+	})
+}
+
+// RevokeInviteHandler withdraws a pending invite. Only owners may revoke invites.
+func RevokeInviteHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	householdID, _ := strconv.Atoi(vars["id"])
+	inviteID, _ := strconv.Atoi(vars["invite_id"])
+	jsonOut := json.NewEncoder(res)
+	if !householdRole(db, userID, int64(householdID)).Allows(households.RoleOwner) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to revoke invites of this household."})
+		return
+	}
+	err := households.NewStore(db).RevokeInvite(int64(householdID), int64(inviteID))
+	if err == households.ErrInviteNotFound {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invite not found."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to revoke invite."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// InviteHandler describes the invite of a token so it can be presented before answering.
+// New users accept by registering with the invite_token at /api/user/register.
+func InviteHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	invite, err := households.NewStore(db).PendingInvite(config.Config.JWTSecret, req.URL.Query().Get("token"))
+	jsonOut := json.NewEncoder(res)
+	if err == households.ErrInvalidInvite {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired invite."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve invite."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(invite)
+}
+
+// AcceptInviteHandler adds the current user to the invite's household.
+// Expected body:
+//   token
+func AcceptInviteHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	user, err := users.NewStore(db).ByID(userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "User not found."})
+		return
+	}
+	householdModel := households.NewStore(db)
+	invite, err := householdModel.AcceptInvite(config.Config.JWTSecret, req.PostFormValue("token"), user.ID, user.Email)
+	switch err {
+	case nil:
+	case households.ErrInvalidInvite:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invalid or expired invite."})
+		return
+	case households.ErrInviteEmailMismatch:
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-3, "Invite was sent to a different email address."})
+		return
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-4, "Unable to accept invite."})
+		return
+	}
+	household, err := householdModel.ForUser(invite.HouseholdID, user.ID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-4, "Unable to accept invite."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(household)
+}
+
+// DeclineInviteHandler refuses an invite. No account is required.
+// Expected body:
+//   token
+func DeclineInviteHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	err := households.NewStore(db).DeclineInvite(config.Config.JWTSecret, req.PostFormValue("token"))
+	jsonOut := json.NewEncoder(res)
+	if err == households.ErrInvalidInvite {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired invite."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to decline invite."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// ContainerByUUIDHandler retrieves a container by its UUID, such as the one encoded in its QR code.
+func ContainerByUUIDHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	container, err := containers.NewStore(db).ByUUID(vars["uuid"])
+	jsonOut := json.NewEncoder(res)
+	if err != nil || !householdRole(db, userID, container.HouseholdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(container)
+}
+
+// ContainerByCodeHandler retrieves a container by its short code, e.g. BOX-0042.
+// Codes are unique within a household; household_id defaults to the personal household.
+func ContainerByCodeHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	householdID, err := queryHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to view this household."})
+		return
+	}
+	container, err := containers.NewStore(db).ByCode(householdID, vars["code"])
+	if err == containers.ErrInvalidCode {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invalid container code."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-3, "Container not found."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(container)
+}
@@ -0,0 +1,177 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// MoveItemsHandler moves one or many items to another container in a single transaction.
+// Expected body:
+//   container_id (the destination container)
+//   item_id (repeated for every item to move)
+//   quantity (optional, repeated in the same order as item_id; 0 moves the whole item)
+func MoveItemsHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	containerID, _ := strconv.Atoi(req.PostFormValue("container_id"))
+	destination, err := containers.NewStore(db).ByID(int64(containerID))
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Destination container not found."})
+		return
+	}
+	if !householdRole(db, userID, destination.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to modify the destination container."})
+		return
+	}
+	itemIDs := req.PostForm["item_id"]
+	quantities := req.PostForm["quantity"]
+	if len(itemIDs) == 0 {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "No items to move."})
+		return
+	}
+	if len(quantities) > 0 && len(quantities) != len(itemIDs) {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, "A quantity is required for every item when quantities are given."})
+		return
+	}
+	itemModel := items.NewStore(db)
+	allowed := make(map[int64]bool)
+	moves := make([]items.ItemMove, len(itemIDs))
+	for i, value := range itemIDs {
+		itemID, _ := strconv.Atoi(value)
+		item, err := itemModel.ByID(int64(itemID))
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			jsonOut.Encode(jsonErrorResponse{-5, "Item not found."})
+			return
+		}
+		householdID := item.Container.HouseholdID
+		if _, ok := allowed[householdID]; !ok {
+			allowed[householdID] = householdRole(db, userID, householdID).Allows(households.RoleEditor)
+		}
+		if !allowed[householdID] {
+			res.WriteHeader(http.StatusForbidden)
+			jsonOut.Encode(jsonErrorResponse{-6, "Not allowed to modify the container of an item."})
+			return
+		}
+		moves[i] = items.ItemMove{ItemID: item.ID, ContainerID: item.Container.ID}
+		if len(quantities) > 0 {
+			moves[i].Quantity, _ = strconv.Atoi(quantities[i])
+		}
+	}
+	results, err := itemModel.Move(moves, &destination)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(map[string][]items.MoveResult{"items": results})
+	case items.ErrItemNotFound:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-7, "An item was changed while moving, please try again."})
+	case items.ErrInvalidQuantity, items.ErrSameContainer, items.ErrDuplicateMove:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-8, err.Error()})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-9, "Unable to move items."})
+	}
+}
+
+// RelocateContainersHandler moves many containers to a location, or to no location, in a single transaction.
+// Containers are either listed or selected with the same filters as listing containers.
+// Expected body:
+//   to_location_id (the target location, blank or 0 removes the location)
+//   container_id (repeated for every container to move)
+//   household_id, location_id, within_location_id, any_tag, all_tag (filters used when no container_id is given)
+func RelocateContainersHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	var location *locations.Location
+	if locationID, _ := strconv.Atoi(req.PostFormValue("to_location_id")); locationID > 0 {
+		target, err := locations.NewStore(db).ByID(int64(locationID))
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			jsonOut.Encode(jsonErrorResponse{-1, "Location not found."})
+			return
+		}
+		if !householdRole(db, userID, target.HouseholdID).Allows(households.RoleEditor) {
+			res.WriteHeader(http.StatusForbidden)
+			jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to add containers to this location."})
+			return
+		}
+		location = &target
+	}
+	containerModel := containers.NewStore(db)
+	var IDs []int64
+	if values := req.PostForm["container_id"]; len(values) > 0 {
+		for _, value := range values {
+			ID, _ := strconv.ParseInt(value, 10, 64)
+			IDs = append(IDs, ID)
+		}
+	} else {
+		filter := containers.ContainerFilter{
+			User:              users.User{ID: userID},
+			LocationIDs:       req.PostForm["location_id"],
+			WithinLocationIDs: req.PostForm["within_location_id"],
+			AnyTags:           req.PostForm["any_tag"],
+			AllTags:           req.PostForm["all_tag"],
+		}
+		filter.HouseholdID, _ = strconv.ParseInt(req.PostFormValue("household_id"), 10, 64)
+		if len(filter.LocationIDs) == 0 && len(filter.WithinLocationIDs) == 0 && len(filter.AnyTags) == 0 && len(filter.AllTags) == 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			jsonOut.Encode(jsonErrorResponse{-3, "Either container_id or a location or tag filter is required."})
+			return
+		}
+		var err error
+		if IDs, err = containerModel.FilteredIDs(filter); err == containers.ErrTooManyContainers {
+			res.WriteHeader(http.StatusBadRequest)
+			jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
+			return
+		} else if err == tags.ErrInvalidName {
+			res.WriteHeader(http.StatusBadRequest)
+			jsonOut.Encode(jsonErrorResponse{-7, err.Error()})
+			return
+		} else if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			jsonOut.Encode(jsonErrorResponse{-5, "Unable to retrieve containers."})
+			return
+		}
+	}
+	allowed := make(map[int64]bool)
+	canEdit := func(householdID int64) bool {
+		if _, ok := allowed[householdID]; !ok {
+			allowed[householdID] = householdRole(db, userID, householdID).Allows(households.RoleEditor)
+		}
+		return allowed[householdID]
+	}
+	results, err := containerModel.Relocate(IDs, location, canEdit)
+	if err == containers.ErrTooManyContainers {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-6, "Unable to relocate containers."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]containers.RelocateResult{"containers": results})
+}
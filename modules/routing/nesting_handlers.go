@@ -0,0 +1,116 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// formParent resolves the parent_id form value. A blank or zero parent_id means no parent.
+func formParent(containerModel *containers.Store, req *http.Request) (*containers.Container, error) {
+	parentID, _ := strconv.Atoi(req.PostFormValue("parent_id"))
+	if parentID <= 0 {
+		return nil, nil
+	}
+	parent, err := containerModel.ByID(int64(parentID))
+	if err != nil {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+func isNestingError(err error) bool {
+	return err == containers.ErrContainerCycle || err == containers.ErrMaxDepth || err == containers.ErrParentHousehold
+}
+
+// ContainerTreeHandler retrieves a container along with every container nested inside it.
+func ContainerTreeHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	containerModel := containers.NewStore(db)
+	containerID, _ := strconv.Atoi(vars["id"])
+	container, err := containerModel.ByID(int64(containerID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
+		return
+	}
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view this container."})
+		return
+	}
+	tree, err := containerModel.Tree(container)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to retrieve nested containers."})
+		return
+	}
+	path, _ := containerModel.Path(container)
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(struct {
+		Path containers.Path           `json:"path"`
+		Tree *containers.ContainerNode `json:"tree"`
+	}{path, tree})
+}
+
+// LocationTreeHandler retrieves every container at a location arranged by nesting.
+func LocationTreeHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	locationID, _ := strconv.Atoi(vars["id"])
+	location, err := locations.NewStore(db).ByID(int64(locationID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Location not found."})
+		return
+	}
+	if !householdRole(db, userID, location.HouseholdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view this location."})
+		return
+	}
+	tree, err := containers.NewStore(db).LocationTree(location.ID, location.HouseholdID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to retrieve containers."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(struct {
+		Location   locations.Location          `json:"location"`
+		Containers []*containers.ContainerNode `json:"containers"`
+	}{location, tree})
+}
+
+// writeLocationParentError reports problems with the parent of a location. Returns false if there were none.
+func writeLocationParentError(res http.ResponseWriter, err error, code int) bool {
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case sql.ErrNoRows:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{code, "Parent location not found."})
+	case locations.ErrLocationCycle, locations.ErrParentHousehold, locations.ErrTooDeep:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+	default:
+		return false
+	}
+	return true
+}
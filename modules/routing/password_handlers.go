@@ -0,0 +1,87 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/mailer"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+// Mailer delivers outgoing email. Without SMTP configuration, messages are written to MAIL_DIR.
+var Mailer mailer.Mailer = newMailer()
+
+func newMailer() mailer.Mailer {
+	if config.Config.SMTPHost == "" {
+		return mailer.FileMailer{Dir: config.Config.MailDir, From: config.Config.MailFrom}
+	}
+	return mailer.SMTPMailer{
+		Host:     config.Config.SMTPHost,
+		Port:     config.Config.SMTPPort,
+		Username: config.Config.SMTPUsername,
+		Password: config.Config.SMTPPassword,
+		From:     config.Config.MailFrom,
+	}
+}
+
+// ForgotPasswordHandler sends a password reset link to the user.
+// The reset is processed in the background so neither the response nor its timing
+// reveals whether the email is registered.
+// Expected body:
+//   email
+func ForgotPasswordHandler(res http.ResponseWriter, req *http.Request) {
+	email := req.PostFormValue("email")
+	background(func() { sendPasswordReset(email) })
+	res.WriteHeader(http.StatusAccepted)
+}
+
+func sendPasswordReset(email string) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	user, token, err := users.NewStore(db).RequestPasswordReset(email, config.Config.PasswordResetTTL)
+	if err == nil {
+		err = Mailer.Send(mailer.Message{
+			To:      user.Email,
+			Subject: "Boxmeup password reset",
+			Body: fmt.Sprintf(
+				"A password reset was requested for your Boxmeup account.\n\n"+
+					"Reset your password here (valid for %v):\n%v/reset-password?token=%v\n\n"+
+					"If you did not request this, you can ignore this email.\n",
+				config.Config.PasswordResetTTL,
+				config.Config.WebHost,
+				url.QueryEscape(token)),
+		})
+	}
+	if err != nil && err != users.ErrUserNotFound {
+		log.Println(err)
+	}
+}
+
+// ResetPasswordHandler sets a new password with a reset token.
+// Expected body:
+//   token
+//   password
+func ResetPasswordHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	err := users.NewStore(db).ResetPassword(req.PostFormValue("token"), req.PostFormValue("password"))
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusNoContent)
+	case users.ErrInvalidResetToken:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired reset token."})
+	case users.ErrEmptyPassword:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-2, "Password must not be empty."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to reset password."})
+	}
+}
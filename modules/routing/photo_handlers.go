@@ -0,0 +1,198 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/blobs"
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/photos"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+func blobStore() blobs.Store {
+	if config.Config.BlobStore == "s3" {
+		return blobs.S3Store{
+			Endpoint:  config.Config.S3Endpoint,
+			Region:    config.Config.S3Region,
+			Bucket:    config.Config.S3Bucket,
+			AccessKey: config.Config.S3AccessKey,
+			SecretKey: config.Config.S3SecretKey,
+		}
+	}
+	return blobs.FileStore{Root: config.Config.BlobDir}
+}
+
+// purgePhotos removes the photos left behind by deleted containers and items.
+// Failures only leave orphans for the next purge, so they are logged rather than reported.
+func purgePhotos(db *sql.DB) {
+	if _, err := photos.NewStore(db, blobStore()).PurgeOrphans(); err != nil {
+		log.Println(err)
+	}
+}
+
+// uploadPhoto reads the "photo" file of a multipart request and attaches it to the owner.
+func uploadPhoto(res http.ResponseWriter, req *http.Request, db *sql.DB, ownerType string, ownerID int64) {
+	jsonOut := json.NewEncoder(res)
+	req.Body = http.MaxBytesReader(res, req.Body, config.Config.PhotoMaxBytes)
+	file, _, err := req.FormFile("photo")
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "A photo no larger than the upload limit is required."})
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "A photo no larger than the upload limit is required."})
+		return
+	}
+	photo, err := photos.NewStore(db, blobStore()).Create(ownerType, ownerID, data)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusCreated)
+		jsonOut.Encode(photo)
+	case photos.ErrUnsupportedImage, photos.ErrImageTooLarge:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
+	default:
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-5, "Unable to save photo."})
+	}
+}
+
+// CreateContainerPhotoHandler attaches an uploaded photo to a container.
+func CreateContainerPhotoHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	containerID, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	container, err := containers.NewStore(db).ByID(containerID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
+		return
+	}
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this container."})
+		return
+	}
+	uploadPhoto(res, req, db, photos.OwnerContainer, container.ID)
+}
+
+// CreateItemPhotoHandler attaches an uploaded photo to an item of a container.
+func CreateItemPhotoHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	vars := mux.Vars(req)
+	containerID, _ := strconv.ParseInt(vars["id"], 10, 64)
+	itemID, _ := strconv.ParseInt(vars["item_id"], 10, 64)
+	item, err := items.NewStore(db).ByID(itemID)
+	if err != nil || item.Container.ID != containerID {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Item not found."})
+		return
+	}
+	if !householdRole(db, userID, item.Container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this item."})
+		return
+	}
+	uploadPhoto(res, req, db, photos.OwnerItem, item.ID)
+}
+
+// accessiblePhoto loads the photo named in the route if the user has at least the given role in its household.
+// Errors are written to the response and reported by returning false.
+func accessiblePhoto(res http.ResponseWriter, req *http.Request, db *sql.DB, store *photos.Store, role households.Role) (photos.Photo, bool) {
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	photo, err := store.ByUUID(mux.Vars(req)["uuid"])
+	var householdID int64
+	if err == nil {
+		householdID, err = store.HouseholdID(photo)
+	}
+	if err != nil {
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Photo not found."})
+		return photo, false
+	}
+	if !householdRole(db, userID, householdID).Allows(role) {
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to access this photo."})
+		return photo, false
+	}
+	return photo, true
+}
+
+func photoHandler(thumbnail bool) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		db, _ := database.GetDBResource()
+		defer db.Close()
+		store := photos.NewStore(db, blobStore())
+		photo, ok := accessiblePhoto(res, req, db, store, households.RoleViewer)
+		if !ok {
+			return
+		}
+		content, err := store.Open(photo, thumbnail)
+		if err != nil {
+			log.Println(err)
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to read photo."})
+			return
+		}
+		defer content.Close()
+		contentType := photo.ContentType
+		if thumbnail {
+			contentType = "image/jpeg"
+		}
+		res.Header().Set("Content-Type", contentType)
+		res.Header().Set("Cache-Control", "private, max-age=86400")
+		io.Copy(res, content)
+	}
+}
+
+// PhotoHandler streams the original image of a photo.
+var PhotoHandler = photoHandler(false)
+
+// PhotoThumbnailHandler streams the thumbnail of a photo.
+var PhotoThumbnailHandler = photoHandler(true)
+
+// DeletePhotoHandler removes a photo from its container or item.
+func DeletePhotoHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	store := photos.NewStore(db, blobStore())
+	photo, ok := accessiblePhoto(res, req, db, store, households.RoleEditor)
+	if !ok {
+		return
+	}
+	if err := store.Delete(photo); err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to delete photo."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/mailer"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+type profileResponse struct {
+	User users.User `json:"user"`
+	// Session is only set when a password change started a new session.
+	Session *users.TokenPair `json:"session,omitempty"`
+}
+
+// ProfileHandler retrieves the account of the current user.
+func ProfileHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	user, err := users.NewStore(db).ByID(userID)
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "User not found."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(profileResponse{User: user})
+}
+
+// UpdateProfileHandler changes the email address and/or password of the current user.
+// Changing the password revokes all sessions; the response contains a new session.
+// Expected body:
+//   email (optional)
+//   password (optional)
+//   current_password
+func UpdateProfileHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	if isAPIKeyRequest(req) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Account details can not be changed with an API key."})
+		return
+	}
+	userModel := users.NewStore(db)
+	previous, err := userModel.ByID(userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "User not found."})
+		return
+	}
+	session, err := userModel.UpdateProfile(authConfig(), userID, users.ProfileUpdate{
+		Email:           req.PostFormValue("email"),
+		Password:        req.PostFormValue("password"),
+		CurrentPassword: req.PostFormValue("current_password"),
+	})
+	switch err {
+	case nil:
+	case users.ErrPasswordMismatch:
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-3, "Current password is incorrect."})
+		return
+	case users.ErrInvalidEmail:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, "Invalid email address."})
+		return
+	case users.ErrEmailTaken:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-5, "Email address is already in use."})
+		return
+	default:
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-6, "Unable to update account."})
+		return
+	}
+	user, _ := userModel.ByID(userID)
+	if user.Email != previous.Email {
+		background(func() { notifyEmailChanged(previous.Email, user.Email) })
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(profileResponse{user, session})
+}
+
+// notifyEmailChanged lets the previous address know in case the change was not made by the owner.
+func notifyEmailChanged(previous string, current string) {
+	err := Mailer.Send(mailer.Message{
+		To:      previous,
+		Subject: "Your Boxmeup email address was changed",
+		Body: fmt.Sprintf(
+			"The email address of your Boxmeup account was changed to %v.\n\n"+
+				"If you did not make this change, reset your password and contact support.\n",
+			current),
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// DeleteAccountHandler permanently removes the current user and all of their inventory.
+// Expected body:
+//   current_password
+func DeleteAccountHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	if isAPIKeyRequest(req) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Accounts can not be deleted with an API key."})
+		return
+	}
+	err := users.NewStore(db).DeleteAccount(authConfig(), userID, req.PostFormValue("current_password"))
+	switch err {
+	case nil:
+		purgePhotos(db)
+		purgeReceipts(db)
+		res.WriteHeader(http.StatusNoContent)
+	case users.ErrPasswordMismatch:
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Current password is incorrect."})
+	default:
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to delete account."})
+	}
+}
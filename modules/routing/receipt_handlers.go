@@ -0,0 +1,146 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/receipts"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// purgeReceipts removes the receipts left behind by deleted items.
+// Failures only leave orphans for the next purge, so they are logged rather than reported.
+func purgeReceipts(db *sql.DB) {
+	if _, err := receipts.NewStore(db, blobStore()).PurgeOrphans(); err != nil {
+		log.Println(err)
+	}
+}
+
+// SaveItemReceiptHandler attaches an uploaded receipt to an item, replacing the receipt it had.
+// Expected body:
+//   receipt (multipart file, a JPEG, PNG or GIF image or a PDF)
+func SaveItemReceiptHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	vars := mux.Vars(req)
+	containerID, _ := strconv.ParseInt(vars["id"], 10, 64)
+	itemID, _ := strconv.ParseInt(vars["item_id"], 10, 64)
+	item, err := items.NewStore(db).ByID(itemID)
+	if err != nil || item.Container.ID != containerID {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Item not found."})
+		return
+	}
+	if !householdRole(db, userID, item.Container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this item."})
+		return
+	}
+	req.Body = http.MaxBytesReader(res, req.Body, config.Config.ReceiptMaxBytes)
+	file, _, err := req.FormFile("receipt")
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "A receipt no larger than the upload limit is required."})
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "A receipt no larger than the upload limit is required."})
+		return
+	}
+	receipt, err := receipts.NewStore(db, blobStore()).Save(item.ID, data)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusCreated)
+		jsonOut.Encode(receipt)
+	case receipts.ErrUnsupportedReceipt:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-4, err.Error()})
+	default:
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-5, "Unable to save receipt."})
+	}
+}
+
+// accessibleReceipt loads the receipt named in the route if the user has at least the given role in its household.
+// Errors are written to the response and reported by returning false.
+func accessibleReceipt(res http.ResponseWriter, req *http.Request, db *sql.DB, store *receipts.Store, role households.Role) (receipts.Receipt, bool) {
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	receipt, err := store.ByUUID(mux.Vars(req)["uuid"])
+	var householdID int64
+	if err == nil {
+		householdID, err = store.HouseholdID(receipt)
+	}
+	if err != nil {
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Receipt not found."})
+		return receipt, false
+	}
+	if !householdRole(db, userID, householdID).Allows(role) {
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to access this receipt."})
+		return receipt, false
+	}
+	return receipt, true
+}
+
+// ReceiptHandler streams the file of a receipt.
+func ReceiptHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	store := receipts.NewStore(db, blobStore())
+	receipt, ok := accessibleReceipt(res, req, db, store, households.RoleViewer)
+	if !ok {
+		return
+	}
+	content, err := store.Open(receipt)
+	if err != nil {
+		log.Println(err)
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to read receipt."})
+		return
+	}
+	defer content.Close()
+	res.Header().Set("Content-Type", receipt.ContentType)
+	res.Header().Set("Cache-Control", "private, max-age=86400")
+	io.Copy(res, content)
+}
+
+// DeleteReceiptHandler removes the receipt of an item.
+func DeleteReceiptHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	store := receipts.NewStore(db, blobStore())
+	receipt, ok := accessibleReceipt(res, req, db, store, households.RoleEditor)
+	if !ok {
+		return
+	}
+	if err := store.Delete(receipt); err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to delete receipt."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -14,5 +14,13 @@ func NewRouter() *mux.Router {
 			Name(route.Name).
 			Handler(route.Handler)
 	}
+	adminRouter := router.PathPrefix("/api/admin").Subrouter()
+	for _, route := range adminRoutes {
+		adminRouter.
+			Methods(route.Method).
+			Path(route.Pattern).
+			Name(route.Name).
+			Handler(route.Handler)
+	}
 	return router
 }
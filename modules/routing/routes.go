@@ -2,13 +2,16 @@ package routing
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
 	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
 	chain "github.com/justinas/alice"
 )
 
@@ -76,18 +79,34 @@ func authHandler(next http.Handler) http.Handler {
 			http.Error(res, "Authorization required.", 401)
 			return
 		}
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			http.Error(res, "Authorization header must be in the form of: Bearer {token}", 401)
-			return
+		parts := strings.SplitN(authHeader, " ", 2)
+		var claims jwt.MapClaims
+		var err error
+		switch {
+		case len(parts) == 2 && strings.ToLower(parts[0]) == "bearer":
+			claims, err = bearerClaims(parts[1])
+		case len(parts) == 2 && strings.ToUpper(parts[0]) == apiKeyAuthScheme:
+			claims, err = apiKeyClaims(parts[1], res, req)
+		default:
+			err = fmt.Errorf("Authorization header must be in the form of: Bearer {token} or %v {params}", apiKeyAuthScheme)
 		}
-		claims, err := users.ValidateAndDecodeAuthClaim(parts[1], users.AuthConfig{
-			JWTSecret: config.Config.JWTSecret,
-		})
-		if err != nil {
+		if err == errAPIRequestTooLarge {
+			http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
 			http.Error(res, err.Error(), 401)
 			return
 		}
+		if err = checkAccountStatus(claims); err != nil {
+			if statusErr, ok := accountStatusErrors[err]; ok {
+				res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+				res.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(res).Encode(statusErr)
+			} else {
+				http.Error(res, "Unable to verify account.", 401)
+			}
+			return
+		}
 		var userKey userKey = "user"
 		newRequest := req.WithContext(context.WithValue(req.Context(), userKey, claims))
 		*req = *newRequest
@@ -97,6 +116,27 @@ func authHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+func checkAccountStatus(claims jwt.MapClaims) error {
+	userID, _ := claims["id"].(float64)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	return users.NewStore(db).AccountStatus(int64(userID))
+}
+
+func bearerClaims(token string) (jwt.MapClaims, error) {
+	claims, err := users.ValidateAndDecodeAuthClaim(token, authConfig())
+	if err != nil {
+		return nil, err
+	}
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	revoked, err := users.NewStore(db).IsRevoked(claims)
+	if err != nil || revoked {
+		return nil, errors.New("Token has been revoked.")
+	}
+	return claims, nil
+}
+
 var routes = Routes{
 	Route{
 		"Index",
@@ -104,18 +144,150 @@ var routes = Routes{
 		"/",
 		chain.New(logHandler).ThenFunc(IndexHandler),
 	},
+	Route{
+		"JWKS",
+		"GET",
+		"/.well-known/jwks.json",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(JWKSHandler),
+	},
 	Route{
 		"Login",
 		"POST",
 		"/api/user/login",
 		chain.New(logHandler, jsonResponseHandler).ThenFunc(LoginHandler),
 	},
+	Route{
+		"TwoFactorLogin",
+		"POST",
+		"/api/user/login/2fa",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(TwoFactorLoginHandler),
+	},
+	Route{
+		"EnrollTwoFactor",
+		"POST",
+		"/api/user/2fa",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(EnrollTwoFactorHandler),
+	},
+	Route{
+		"TwoFactorQR",
+		"GET",
+		"/api/user/2fa/qrcode",
+		chain.New(logHandler, authHandler).ThenFunc(TwoFactorQRHandler),
+	},
+	Route{
+		"ConfirmTwoFactor",
+		"POST",
+		"/api/user/2fa/confirm",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ConfirmTwoFactorHandler),
+	},
+	Route{
+		"DisableTwoFactor",
+		"POST",
+		"/api/user/2fa/disable",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DisableTwoFactorHandler),
+	},
+	Route{
+		"Refresh",
+		"POST",
+		"/api/user/refresh",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(RefreshHandler),
+	},
+	Route{
+		"Logout",
+		"POST",
+		"/api/user/logout",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(LogoutHandler),
+	},
+	Route{
+		"Profile",
+		"GET",
+		"/api/user/me",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ProfileHandler),
+	},
+	Route{
+		"UpdateProfile",
+		"PUT",
+		"/api/user/me",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(UpdateProfileHandler),
+	},
+	Route{
+		"DeleteAccount",
+		"POST",
+		"/api/user/me/delete",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteAccountHandler),
+	},
+	Route{
+		"SecurityEvents",
+		"GET",
+		"/api/user/security/events",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(SecurityEventsHandler),
+	},
+	Route{
+		"ForgotPassword",
+		"POST",
+		"/api/user/password/forgot",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(ForgotPasswordHandler),
+	},
+	Route{
+		"ResetPassword",
+		"POST",
+		"/api/user/password/reset",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(ResetPasswordHandler),
+	},
+	Route{
+		"VerifyEmail",
+		"POST",
+		"/api/user/verify",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(VerifyEmailHandler),
+	},
+	Route{
+		"ResendVerification",
+		"POST",
+		"/api/user/verify/resend",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(ResendVerificationHandler),
+	},
 	Route{
 		"Register",
 		"POST",
 		"/api/user/register",
 		chain.New(logHandler, jsonResponseHandler).ThenFunc(RegisterHandler),
 	},
+	Route{
+		"CreateAPIKey",
+		"POST",
+		"/api/user/apikey",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateAPIKeyHandler),
+	},
+	Route{
+		"APIKeys",
+		"GET",
+		"/api/user/apikey",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(APIKeysHandler),
+	},
+	Route{
+		"RevokeAPIKey",
+		"DELETE",
+		"/api/user/apikey/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RevokeAPIKeyHandler),
+	},
+	Route{
+		"RelocateContainers",
+		"POST",
+		"/api/container/relocate",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RelocateContainersHandler),
+	},
+	Route{
+		"ContainerByUUID",
+		"GET",
+		"/api/container/by-uuid/{uuid}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ContainerByUUIDHandler),
+	},
+	Route{
+		"ContainerByCode",
+		"GET",
+		"/api/container/by-code/{code}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ContainerByCodeHandler),
+	},
 	Route{
 		"CreateContainer",
 		"POST",
@@ -140,6 +312,18 @@ var routes = Routes{
 		"/api/container/{id}",
 		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ContainerHandler),
 	},
+	Route{
+		"ContainerTree",
+		"GET",
+		"/api/container/{id}/tree",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ContainerTreeHandler),
+	},
+	Route{
+		"SetContainerTags",
+		"PUT",
+		"/api/container/{id}/tags",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(SetContainerTagsHandler),
+	},
 	Route{
 		"Containers",
 		"GET",
@@ -170,6 +354,60 @@ var routes = Routes{
 		"/api/container/{id}/item/{item_id}",
 		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteContainerItemHandler),
 	},
+	Route{
+		"CreateContainerPhoto",
+		"POST",
+		"/api/container/{id}/photo",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateContainerPhotoHandler),
+	},
+	Route{
+		"CreateItemPhoto",
+		"POST",
+		"/api/container/{id}/item/{item_id}/photo",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateItemPhotoHandler),
+	},
+	Route{
+		"SaveItemReceipt",
+		"POST",
+		"/api/container/{id}/item/{item_id}/receipt",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(SaveItemReceiptHandler),
+	},
+	Route{
+		"Photo",
+		"GET",
+		"/api/photo/{uuid}",
+		chain.New(logHandler, authHandler).ThenFunc(PhotoHandler),
+	},
+	Route{
+		"PhotoThumbnail",
+		"GET",
+		"/api/photo/{uuid}/thumbnail",
+		chain.New(logHandler, authHandler).ThenFunc(PhotoThumbnailHandler),
+	},
+	Route{
+		"DeletePhoto",
+		"DELETE",
+		"/api/photo/{uuid}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeletePhotoHandler),
+	},
+	Route{
+		"Receipt",
+		"GET",
+		"/api/receipt/{uuid}",
+		chain.New(logHandler, authHandler).ThenFunc(ReceiptHandler),
+	},
+	Route{
+		"DeleteReceipt",
+		"DELETE",
+		"/api/receipt/{uuid}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteReceiptHandler),
+	},
+	Route{
+		"InventoryReport",
+		"GET",
+		"/api/report/inventory",
+		chain.New(logHandler, authHandler).ThenFunc(InventoryReportHandler),
+	},
 	Route{
 		"Items",
 		"GET",
@@ -182,6 +420,66 @@ var routes = Routes{
 		"/api/item/search",
 		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(SearchItemHandler),
 	},
+	Route{
+		"ItemsByBarcode",
+		"GET",
+		"/api/item/barcode",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(BarcodeHandler),
+	},
+	Route{
+		"MoveItems",
+		"POST",
+		"/api/item/move",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(MoveItemsHandler),
+	},
+	Route{
+		"Tags",
+		"GET",
+		"/api/tag",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(TagsHandler),
+	},
+	Route{
+		"CreateTag",
+		"POST",
+		"/api/tag",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateTagHandler),
+	},
+	Route{
+		"UpdateTag",
+		"PUT",
+		"/api/tag/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(UpdateTagHandler),
+	},
+	Route{
+		"DeleteTag",
+		"DELETE",
+		"/api/tag/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteTagHandler),
+	},
+	Route{
+		"Fields",
+		"GET",
+		"/api/field",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(FieldsHandler),
+	},
+	Route{
+		"CreateField",
+		"POST",
+		"/api/field",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateFieldHandler),
+	},
+	Route{
+		"UpdateField",
+		"PUT",
+		"/api/field/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(UpdateFieldHandler),
+	},
+	Route{
+		"DeleteField",
+		"DELETE",
+		"/api/field/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteFieldHandler),
+	},
 	Route{
 		"CreateLocation",
 		"POST",
@@ -200,10 +498,194 @@ var routes = Routes{
 		"/api/location/{id}",
 		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(DeleteLocationHandler),
 	},
+	Route{
+		"LocationTree",
+		"GET",
+		"/api/location/{id}/tree",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(LocationTreeHandler),
+	},
 	Route{
 		"Locations",
 		"GET",
 		"/api/location",
 		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(LocationsHandler),
 	},
+	Route{
+		"Households",
+		"GET",
+		"/api/household",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(HouseholdsHandler),
+	},
+	Route{
+		"CreateHousehold",
+		"POST",
+		"/api/household",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateHouseholdHandler),
+	},
+	Route{
+		"Household",
+		"GET",
+		"/api/household/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(HouseholdHandler),
+	},
+	Route{
+		"UpdateHousehold",
+		"PUT",
+		"/api/household/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(UpdateHouseholdHandler),
+	},
+	Route{
+		"UpdateHouseholdMember",
+		"PUT",
+		"/api/household/{id}/member/{user_id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(UpdateHouseholdMemberHandler),
+	},
+	Route{
+		"RemoveHouseholdMember",
+		"DELETE",
+		"/api/household/{id}/member/{user_id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RemoveHouseholdMemberHandler),
+	},
+	Route{
+		"CreateInvite",
+		"POST",
+		"/api/household/{id}/invite",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateInviteHandler),
+	},
+	Route{
+		"Invites",
+		"GET",
+		"/api/household/{id}/invite",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(InvitesHandler),
+	},
+	Route{
+		"RevokeInvite",
+		"DELETE",
+		"/api/household/{id}/invite/{invite_id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RevokeInviteHandler),
+	},
+	Route{
+		"Invite",
+		"GET",
+		"/api/invite",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(InviteHandler),
+	},
+	Route{
+		"AcceptInvite",
+		"POST",
+		"/api/invite/accept",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(AcceptInviteHandler),
+	},
+	Route{
+		"DeclineInvite",
+		"POST",
+		"/api/invite/decline",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(DeclineInviteHandler),
+	},
+	Route{
+		"CreateContainerShare",
+		"POST",
+		"/api/container/{id}/share",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateContainerShareHandler),
+	},
+	Route{
+		"ContainerShares",
+		"GET",
+		"/api/container/{id}/share",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(ContainerSharesHandler),
+	},
+	Route{
+		"CreateLocationShare",
+		"POST",
+		"/api/location/{id}/share",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(CreateLocationShareHandler),
+	},
+	Route{
+		"LocationShares",
+		"GET",
+		"/api/location/{id}/share",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(LocationSharesHandler),
+	},
+	Route{
+		"RevokeShare",
+		"DELETE",
+		"/api/share/{id}",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RevokeShareHandler),
+	},
+	Route{
+		"Trash",
+		"GET",
+		"/api/trash",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(TrashHandler),
+	},
+	Route{
+		"RestoreLocation",
+		"POST",
+		"/api/trash/location/{id}/restore",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RestoreLocationHandler),
+	},
+	Route{
+		"RestoreContainer",
+		"POST",
+		"/api/trash/container/{id}/restore",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RestoreContainerHandler),
+	},
+	Route{
+		"RestoreItem",
+		"POST",
+		"/api/trash/item/{id}/restore",
+		chain.New(logHandler, authHandler, jsonResponseHandler).ThenFunc(RestoreItemHandler),
+	},
+	Route{
+		"Shared",
+		"GET",
+		"/share/{token}",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(SharedHandler),
+	},
+	Route{
+		"SharedContainer",
+		"GET",
+		"/share/{token}/container/{uuid}",
+		chain.New(logHandler, jsonResponseHandler).ThenFunc(SharedContainerHandler),
+	},
+}
+
+// adminRoutes are mounted under /api/admin and restricted to administrators.
+var adminRoutes = Routes{
+	Route{
+		"AdminUsers",
+		"GET",
+		"/user",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminUsersHandler),
+	},
+	Route{
+		"AdminUser",
+		"GET",
+		"/user/{id}",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminUserHandler),
+	},
+	Route{
+		"AdminDeactivateUser",
+		"POST",
+		"/user/{id}/deactivate",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminDeactivateUserHandler),
+	},
+	Route{
+		"AdminReactivateUser",
+		"POST",
+		"/user/{id}/reactivate",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminReactivateUserHandler),
+	},
+	Route{
+		"AdminForcePasswordReset",
+		"POST",
+		"/user/{id}/password/reset",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminForcePasswordResetHandler),
+	},
+	Route{
+		"AdminAuditLog",
+		"GET",
+		"/audit",
+		chain.New(logHandler, authHandler, adminHandler, jsonResponseHandler).ThenFunc(AdminAuditLogHandler),
+	},
 }
@@ -0,0 +1,217 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	"github.com/cjsaylor/boxmeup-go/modules/shares"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+type shareLinkResponse struct {
+	shares.Link
+	URL string `json:"url"`
+}
+
+// shareTargetHousehold resolves the household of the container or location being shared.
+func shareTargetHousehold(db *sql.DB, targetType string, targetID int64) (int64, error) {
+	if targetType == shares.TargetLocation {
+		location, err := locations.NewStore(db).ByID(targetID)
+		return location.HouseholdID, err
+	}
+	container, err := containers.NewStore(db).ByID(targetID)
+	return container.HouseholdID, err
+}
+
+// createShareHandler mints share links for the target type. Editors may share.
+// Expected body:
+//   expires_in (optional duration such as 72h, never expires when omitted)
+func createShareHandler(targetType string) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		db, _ := database.GetDBResource()
+		defer db.Close()
+		var userKey userKey = "user"
+		userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+		targetID, _ := strconv.Atoi(vars["id"])
+		jsonOut := json.NewEncoder(res)
+		householdID, err := shareTargetHousehold(db, targetType, int64(targetID))
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			jsonOut.Encode(jsonErrorResponse{-1, "Not found."})
+			return
+		}
+		if !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+			res.WriteHeader(http.StatusForbidden)
+			jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to share this " + targetType + "."})
+			return
+		}
+		var ttl time.Duration
+		if expiresIn := req.PostFormValue("expires_in"); expiresIn != "" {
+			ttl, err = time.ParseDuration(expiresIn)
+			if err != nil || ttl <= 0 {
+				res.WriteHeader(http.StatusBadRequest)
+				jsonOut.Encode(jsonErrorResponse{-3, "Invalid expires_in duration."})
+				return
+			}
+		}
+		link, err := shares.NewStore(db).Create(userID, householdID, targetType, int64(targetID), ttl)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			jsonOut.Encode(jsonErrorResponse{-4, "Unable to create share link."})
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(shareLinkResponse{link, fmt.Sprintf("%v/share/%v", config.Config.WebHost, link.Token)})
+	}
+}
+
+// sharesHandler lists the share links of a container or location. Editors may list them.
+func sharesHandler(targetType string) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		vars := mux.Vars(req)
+		db, _ := database.GetDBResource()
+		defer db.Close()
+		var userKey userKey = "user"
+		userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+		targetID, _ := strconv.Atoi(vars["id"])
+		jsonOut := json.NewEncoder(res)
+		householdID, err := shareTargetHousehold(db, targetType, int64(targetID))
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			jsonOut.Encode(jsonErrorResponse{-1, "Not found."})
+			return
+		}
+		if !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+			res.WriteHeader(http.StatusForbidden)
+			jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to view share links of this " + targetType + "."})
+			return
+		}
+		links, err := shares.NewStore(db).Links(targetType, int64(targetID))
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			jsonOut.Encode(jsonErrorResponse{-3, "Unable to retrieve share links."})
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(map[string][]shares.Link{
+			"shares": links,
+		})
+	}
+}
+
+// CreateContainerShareHandler mints a public read-only link to a container.
+var CreateContainerShareHandler = createShareHandler(shares.TargetContainer)
+
+// ContainerSharesHandler lists the share links of a container.
+var ContainerSharesHandler = sharesHandler(shares.TargetContainer)
+
+// CreateLocationShareHandler mints a public read-only link to a location and its containers.
+var CreateLocationShareHandler = createShareHandler(shares.TargetLocation)
+
+// LocationSharesHandler lists the share links of a location.
+var LocationSharesHandler = sharesHandler(shares.TargetLocation)
+
+// RevokeShareHandler disables a share link. Editors of the shared household may revoke.
+func RevokeShareHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	shareID, _ := strconv.Atoi(vars["id"])
+	shareModel := shares.NewStore(db)
+	link, err := shareModel.ByID(int64(shareID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Share link not found."})
+		return
+	}
+	if !householdRole(db, userID, link.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to revoke this share link."})
+		return
+	}
+	if err = shareModel.Revoke(link.ID); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to revoke share link."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func writeShareError(res http.ResponseWriter, jsonOut *json.Encoder, err error) {
+	switch err {
+	case shares.ErrInvalidShare:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired share link."})
+	case shares.ErrNotShared:
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not found."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to retrieve shared inventory."})
+	}
+}
+
+// SharedHandler shows what a share link exposes without authentication.
+// Container links return the container, its location and a page of its items.
+// Location links return the location and its containers.
+func SharedHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	shareModel := shares.NewStore(db)
+	jsonOut := json.NewEncoder(res)
+	link, err := shareModel.Resolve(vars["token"])
+	if err != nil {
+		writeShareError(res, jsonOut, err)
+		return
+	}
+	var view interface{}
+	if link.TargetType == shares.TargetLocation {
+		view, err = shareModel.Location(link)
+	} else {
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		view, err = shareModel.Container(link, "", page)
+	}
+	if err != nil {
+		writeShareError(res, jsonOut, err)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(view)
+}
+
+// SharedContainerHandler shows a container of a shared location with a page of its items.
+func SharedContainerHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	shareModel := shares.NewStore(db)
+	jsonOut := json.NewEncoder(res)
+	link, err := shareModel.Resolve(vars["token"])
+	if err != nil {
+		writeShareError(res, jsonOut, err)
+		return
+	}
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	view, err := shareModel.Container(link, vars["uuid"], page)
+	if err != nil {
+		writeShareError(res, jsonOut, err)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(view)
+}
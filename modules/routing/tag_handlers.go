@@ -0,0 +1,168 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+func writeTagError(res http.ResponseWriter, err error, code int) {
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case tags.ErrInvalidName:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+	case tags.ErrTagExists:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{code, err.Error()})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{code, "Unable to save the tag."})
+	}
+}
+
+// TagsHandler lists the tags of a household with the number of containers using each, for a tag cloud.
+// Accepts household_id, defaulting to the personal household.
+func TagsHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	householdID, err := queryHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to view this household."})
+		return
+	}
+	usage, err := tags.NewStore(db).Usage(householdID)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to retrieve tags."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string][]tags.Usage{"tags": usage})
+}
+
+// CreateTagHandler adds a tag to the vocabulary of a household.
+// Expected body:
+//   name
+//   household_id (optional, defaults to the personal household)
+func CreateTagHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	householdID, err := targetHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to add tags to this household."})
+		return
+	}
+	tag, err := tags.NewStore(db).Create(householdID, req.PostFormValue("name"))
+	if err != nil {
+		writeTagError(res, err, -2)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(tag)
+}
+
+// editableTag retrieves the tag of the request if the user may change it.
+func editableTag(res http.ResponseWriter, req *http.Request, tagModel *tags.Store, userID int64) (tags.Tag, bool) {
+	jsonOut := json.NewEncoder(res)
+	tagID, _ := strconv.Atoi(mux.Vars(req)["id"])
+	tag, err := tagModel.ByID(int64(tagID))
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Tag not found."})
+		return tag, false
+	}
+	if !householdRole(tagModel.DB, userID, tag.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to modify this tag."})
+		return tag, false
+	}
+	return tag, true
+}
+
+// UpdateTagHandler renames a tag everywhere it is used.
+// Expected body:
+//   name
+func UpdateTagHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	tagModel := tags.NewStore(db)
+	tag, ok := editableTag(res, req, tagModel, userID)
+	if !ok {
+		return
+	}
+	if err := tagModel.Rename(tag.ID, req.PostFormValue("name")); err != nil {
+		writeTagError(res, err, -3)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteTagHandler removes a tag from the vocabulary and from every container.
+func DeleteTagHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	tagModel := tags.NewStore(db)
+	tag, ok := editableTag(res, req, tagModel, userID)
+	if !ok {
+		return
+	}
+	if err := tagModel.Delete(tag.ID); err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-3, "Unable to remove the tag."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// SetContainerTagsHandler replaces the tags of a container.
+// Tags that are not yet in the household's vocabulary are added to it.
+// Expected body:
+//   tag (repeated for every tag name, none removes all tags)
+func SetContainerTagsHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	containerID, _ := strconv.Atoi(vars["id"])
+	container, err := containers.NewStore(db).ByID(int64(containerID))
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found."})
+		return
+	}
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to edit this container."})
+		return
+	}
+	req.ParseForm()
+	tagged, err := tags.NewStore(db).SetContainerTags(container.ID, container.HouseholdID, req.PostForm["tag"])
+	if err != nil {
+		writeTagError(res, err, -3)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(map[string]tags.Tags{"tags": tagged})
+}
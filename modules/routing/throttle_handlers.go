@@ -0,0 +1,158 @@
+package routing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/throttle"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// accountPolicy throttles guesses against a single account.
+	accountPolicy = throttle.Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	}
+	// ipPolicy is more lenient as many users may share an address.
+	ipPolicy = throttle.Policy{
+		FreeAttempts:     20,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}
+	memoryThrottleStore = throttle.NewMemoryStore()
+)
+
+func throttleStore(db *sql.DB) throttle.Store {
+	if config.Config.LoginThrottleStore == "database" {
+		return throttle.NewDBStore(db)
+	}
+	return memoryThrottleStore
+}
+
+// clientIP identifies the client making the request.
+// Behind a trusted proxy the last X-Forwarded-For entry is the address the proxy saw.
+func clientIP(req *http.Request) string {
+	if config.Config.TrustProxyHeaders {
+		if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// loginThrottle applies the account and IP address policies to a login attempt.
+type loginThrottle struct {
+	IP         string
+	account    *throttle.Limiter
+	ip         *throttle.Limiter
+	accountKey string
+	ipKey      string
+}
+
+func newLoginThrottle(db *sql.DB, req *http.Request, account string) *loginThrottle {
+	store := throttleStore(db)
+	ip := clientIP(req)
+	return &loginThrottle{
+		IP:         ip,
+		account:    throttle.NewLimiter(store, accountPolicy),
+		ip:         throttle.NewLimiter(store, ipPolicy),
+		accountKey: "account:" + strings.ToLower(strings.TrimSpace(account)),
+		ipKey:      "ip:" + ip,
+	}
+}
+
+// retryAfter is how long the client must wait before attempting to log in again.
+// Throttling fails open if the store is unavailable.
+func (t *loginThrottle) retryAfter() time.Duration {
+	var wait time.Duration
+	for _, check := range []struct {
+		limiter *throttle.Limiter
+		key     string
+	}{{t.account, t.accountKey}, {t.ip, t.ipKey}} {
+		d, err := check.limiter.Check(check.key)
+		if err != nil {
+			log.Println(err)
+		} else if d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// failure records a failed attempt, reporting if the account has just been locked out.
+func (t *loginThrottle) failure() bool {
+	_, lockedOut, err := t.account.Failure(t.accountKey)
+	if err != nil {
+		log.Println(err)
+	}
+	if _, _, err = t.ip.Failure(t.ipKey); err != nil {
+		log.Println(err)
+	}
+	return lockedOut
+}
+
+// success clears the account's failures. Address failures are kept so a
+// successful login to one account can not reset guessing against others.
+func (t *loginThrottle) success() {
+	if err := t.account.Success(t.accountKey); err != nil {
+		log.Println(err)
+	}
+}
+
+func recordSecurityEvents(userModel *users.Store, userID int64, ip string, events ...string) {
+	for _, event := range events {
+		if err := userModel.RecordSecurityEvent(userID, event, ip); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func writeThrottled(res http.ResponseWriter, code int, wait time.Duration) {
+	res.Header().Set("Retry-After", fmt.Sprintf("%d", int64(math.Ceil(wait.Seconds()))))
+	res.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(res).Encode(jsonErrorResponse{code, "Too many failed login attempts. Try again later."})
+}
+
+type securityEventsResponse struct {
+	Events []users.SecurityEvent `json:"events"`
+}
+
+// SecurityEventsHandler lists recent failed logins and lockouts of the current user.
+func SecurityEventsHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	events, err := users.NewStore(db).SecurityEvents(userID)
+	jsonOut := json.NewEncoder(res)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-1, "Unable to retrieve security events."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(securityEventsResponse{events})
+}
@@ -0,0 +1,197 @@
+package routing
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+)
+
+// PurgeTrash permanently deletes everything that has been in the trash longer than the retention period.
+// Photos of the purged containers and items are removed along with them.
+// It runs outside of any request, so a panic, such as database.GetDBResource failing to reach MySQL,
+// is logged and the purge is tried again on the next interval.
+func PurgeTrash() {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println(err)
+		}
+	}()
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	before := time.Now().Add(-config.Config.TrashRetention)
+	purges := []struct {
+		kind  string
+		purge func(before time.Time) (int64, error)
+	}{
+		{"items", items.NewStore(db).PurgeTrash},
+		{"containers", containers.NewStore(db).PurgeTrash},
+		{"locations", locations.NewStore(db).PurgeTrash},
+	}
+	for _, p := range purges {
+		count, err := p.purge(before)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Purged %v %v from the trash", count, p.kind)
+		}
+	}
+	purgePhotos(db)
+	purgeReceipts(db)
+}
+
+// StartTrashPurge purges the trash on an interval for as long as the server runs.
+func StartTrashPurge() {
+	go func() {
+		for {
+			PurgeTrash()
+			time.Sleep(config.Config.TrashPurgeInterval)
+		}
+	}()
+}
+
+// TrashHandler lists the locations, containers and items in the trash of the user.
+func TrashHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	trashedLocations, err := locations.NewStore(db).Trashed(userID)
+	var trashedContainers []containers.Container
+	if err == nil {
+		trashedContainers, err = containers.NewStore(db).Trashed(userID)
+	}
+	var trashedItems items.ContainerItems
+	if err == nil {
+		trashedItems, err = items.NewStore(db).Trashed(userID)
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-1, "Unable to retrieve the trash."})
+		return
+	}
+	jsonOut.Encode(map[string]interface{}{
+		"locations":      trashedLocations,
+		"containers":     trashedContainers,
+		"items":          trashedItems,
+		"retention_days": int(config.Config.TrashRetention.Hours() / 24),
+	})
+}
+
+// trashedID reads the ID of a trashed entry from the route.
+func trashedID(req *http.Request) int64 {
+	ID, _ := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	return ID
+}
+
+// RestoreLocationHandler takes a location out of the trash.
+func RestoreLocationHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	locationModel := locations.NewStore(db)
+	location, err := locationModel.TrashedByID(trashedID(req), userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Location not found in your trash."})
+		return
+	}
+	if !householdRole(db, userID, location.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to restore this location."})
+		return
+	}
+	if err = locationModel.Restore(location); err == nil {
+		location, err = locationModel.ByID(location.ID)
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to restore location."})
+		return
+	}
+	jsonOut.Encode(location)
+}
+
+// RestoreContainerHandler takes a container, along with its items, out of the trash.
+func RestoreContainerHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	containerModel := containers.NewStore(db)
+	container, err := containerModel.TrashedByID(trashedID(req), userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Container not found in your trash."})
+		return
+	}
+	if !householdRole(db, userID, container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to restore this container."})
+		return
+	}
+	if err = containerModel.Restore(container); err == nil {
+		container, err = containerModel.ByID(container.ID)
+	}
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to restore container."})
+		return
+	}
+	jsonOut.Encode(container)
+}
+
+// RestoreItemHandler puts an item from the trash back into its container.
+func RestoreItemHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	jsonOut := json.NewEncoder(res)
+	itemModel := items.NewStore(db)
+	item, err := itemModel.TrashedByID(trashedID(req), userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "Item not found in your trash."})
+		return
+	}
+	if item.Container != nil && !householdRole(db, userID, item.Container.HouseholdID).Allows(households.RoleEditor) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Not allowed to restore this item."})
+		return
+	}
+	err = itemModel.Restore(item)
+	if err == nil {
+		item, err = itemModel.ByID(item.ID)
+	}
+	switch err {
+	case nil:
+		jsonOut.Encode(item)
+	case items.ErrContainerInTrash:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-3, err.Error()})
+	default:
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-4, "Unable to restore item."})
+	}
+}
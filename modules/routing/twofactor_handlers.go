@@ -0,0 +1,180 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool `json:"two_factor_required"`
+	*users.TwoFactorChallenge
+}
+
+// TwoFactorLoginHandler completes a login for users with two factor authentication.
+// Expected body:
+//   challenge_token
+//   code (TOTP or recovery code)
+func TwoFactorLoginHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	challenge := req.PostFormValue("challenge_token")
+	// Codes are throttled per challenged user so a new challenge does not reset guessing.
+	userID, _ := users.ChallengeUserID(authConfig(), challenge)
+	limiter := newLoginThrottle(db, req, fmt.Sprintf("2fa:%d", userID))
+	if wait := limiter.retryAfter(); wait > 0 {
+		writeThrottled(res, -4, wait)
+		return
+	}
+	userModel := users.NewStore(db)
+	tokens, err := userModel.CompleteTwoFactorLogin(authConfig(), challenge, req.PostFormValue("code"))
+	jsonOut := json.NewEncoder(res)
+	if statusErr, ok := accountStatusErrors[err]; ok {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(statusErr)
+		return
+	}
+	switch err {
+	case nil:
+		limiter.success()
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(tokens)
+	case users.ErrInvalidChallenge:
+		res.WriteHeader(http.StatusUnauthorized)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired challenge."})
+	case users.ErrInvalidTwoFactorCode:
+		events := []string{users.SecurityEventTwoFactorFailed}
+		if limiter.failure() {
+			events = append(events, users.SecurityEventAccountLocked)
+		}
+		recordSecurityEvents(userModel, userID, limiter.IP, events...)
+		res.WriteHeader(http.StatusUnauthorized)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invalid two factor code."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Authentication failure."})
+	}
+}
+
+// EnrollTwoFactorHandler starts two factor enrollment, returning the secret and otpauth URL.
+// The QR code for the enrollment is available at /api/user/2fa/qrcode until it is confirmed.
+func EnrollTwoFactorHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	userModel := users.NewStore(db)
+	jsonOut := json.NewEncoder(res)
+	user, err := userModel.ByID(userID)
+	if err != nil {
+		res.WriteHeader(http.StatusNotFound)
+		jsonOut.Encode(jsonErrorResponse{-1, "User not found."})
+		return
+	}
+	enrollment, err := userModel.EnrollTwoFactor(user, config.Config.TOTPIssuer)
+	if err == users.ErrTwoFactorAlreadyEnabled {
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-2, "Two factor authentication is already enabled."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to start two factor enrollment."})
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(enrollment)
+}
+
+// TwoFactorQRHandler outputs a QR code png of the pending enrollment for authenticator apps.
+func TwoFactorQRHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	userModel := users.NewStore(db)
+	user, err := userModel.ByID(userID)
+	if err != nil {
+		http.Error(res, "User not found.", http.StatusNotFound)
+		return
+	}
+	enrollment, err := userModel.PendingTwoFactorEnrollment(user, config.Config.TOTPIssuer)
+	if err != nil {
+		http.Error(res, "No pending two factor enrollment.", http.StatusNotFound)
+		return
+	}
+	qrBytes, err := qrcode.Encode(enrollment.URL, qrcode.Medium, 250)
+	if err != nil {
+		http.Error(res, "Unable to generate QR code.", http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "image/png")
+	res.Header().Set("Cache-Control", "no-store")
+	res.Write(qrBytes)
+}
+
+// ConfirmTwoFactorHandler enables two factor authentication and returns the recovery codes.
+// Expected body:
+//   code
+func ConfirmTwoFactorHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	codes, err := users.NewStore(db).ConfirmTwoFactor(userID, req.PostFormValue("code"))
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusOK)
+		jsonOut.Encode(map[string][]string{
+			"recovery_codes": codes,
+		})
+	case users.ErrTwoFactorNotEnrolled:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-1, "Two factor enrollment has not been started."})
+	case users.ErrTwoFactorAlreadyEnabled:
+		res.WriteHeader(http.StatusConflict)
+		jsonOut.Encode(jsonErrorResponse{-2, "Two factor authentication is already enabled."})
+	case users.ErrInvalidTwoFactorCode:
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-3, "Invalid two factor code."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-4, "Unable to enable two factor authentication."})
+	}
+}
+
+// DisableTwoFactorHandler turns off two factor authentication.
+// Expected body:
+//   password
+//   code (TOTP or recovery code)
+func DisableTwoFactorHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	err := users.NewStore(db).DisableTwoFactor(
+		authConfig(),
+		userID,
+		req.PostFormValue("password"),
+		req.PostFormValue("code"))
+	jsonOut := json.NewEncoder(res)
+	switch err {
+	case nil:
+		res.WriteHeader(http.StatusNoContent)
+	case users.ErrPasswordMismatch:
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Password is incorrect."})
+	case users.ErrInvalidTwoFactorCode:
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-2, "Invalid two factor code."})
+	default:
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-3, "Unable to disable two factor authentication."})
+	}
+}
@@ -0,0 +1,100 @@
+package routing
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/households"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/reports"
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func optionalAmount(value string) (*valuation.Amount, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	amount, err := valuation.ParseAmount(value)
+	return &amount, err
+}
+
+// applyItemValuation sets the purchase details of an item from the purchase_price, replacement_value,
+// currency and purchase_date of a request. Empty values remove them.
+// Prices saved without a currency use the default currency, and items without a price have none.
+func applyItemValuation(res http.ResponseWriter, req *http.Request, item *items.ContainerItem) bool {
+	var err error
+	if values, ok := req.PostForm["purchase_price"]; ok {
+		item.PurchasePrice, err = optionalAmount(values[0])
+	}
+	if values, ok := req.PostForm["replacement_value"]; ok && err == nil {
+		item.ReplacementValue, err = optionalAmount(values[0])
+	}
+	if values, ok := req.PostForm["currency"]; ok && err == nil {
+		item.Currency = ""
+		if strings.TrimSpace(values[0]) != "" {
+			item.Currency, err = valuation.NormalizeCurrency(values[0])
+		}
+	}
+	if values, ok := req.PostForm["purchase_date"]; ok && err == nil {
+		item.PurchaseDate = nil
+		if strings.TrimSpace(values[0]) != "" {
+			var date valuation.Date
+			date, err = valuation.ParseDate(values[0])
+			item.PurchaseDate = &date
+		}
+	}
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(res).Encode(jsonErrorResponse{-8, err.Error()})
+		return false
+	}
+	if item.PurchasePrice == nil && item.ReplacementValue == nil {
+		item.Currency = ""
+	} else if item.Currency == "" {
+		item.Currency = config.Config.DefaultCurrency
+	}
+	return true
+}
+
+// InventoryReportHandler lists every item of a household with its value, grouped by location, for an insurance claim.
+// Expected query:
+//   household_id (optional, defaults to the personal household)
+//   format (optional, json or csv)
+func InventoryReportHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	var userKey userKey = "user"
+	userID := int64(req.Context().Value(userKey).(jwt.MapClaims)["id"].(float64))
+	res.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	jsonOut := json.NewEncoder(res)
+	householdID, err := queryHousehold(db, userID, req)
+	if err != nil || !householdRole(db, userID, householdID).Allows(households.RoleViewer) {
+		res.WriteHeader(http.StatusForbidden)
+		jsonOut.Encode(jsonErrorResponse{-1, "Not allowed to view this household."})
+		return
+	}
+	inventory, err := reports.BuildInventory(db, householdID)
+	if err != nil {
+		log.Println(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to build the inventory."})
+		return
+	}
+	if req.URL.Query().Get("format") == "csv" {
+		filename := "inventory-" + inventory.Generated.Format("2006-01-02") + ".csv"
+		res.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		res.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		res.WriteHeader(http.StatusOK)
+		if err = inventory.WriteCSV(res); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+	jsonOut.Encode(inventory)
+}
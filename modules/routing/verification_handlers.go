@@ -0,0 +1,79 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/cjsaylor/boxmeup-go/modules/config"
+	"github.com/cjsaylor/boxmeup-go/modules/database"
+	"github.com/cjsaylor/boxmeup-go/modules/mailer"
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+// accountStatusErrors maps account status errors to their response codes.
+var accountStatusErrors = map[error]jsonErrorResponse{
+	users.ErrAccountNotVerified: {-10, "Account email address has not been verified."},
+	users.ErrAccountDeactivated: {-11, "Account has been deactivated."},
+}
+
+func sendVerificationEmail(user users.User) error {
+	token, err := users.VerificationToken(authConfig(), user, config.Config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	return Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Boxmeup account",
+		Body: fmt.Sprintf(
+			"Welcome to Boxmeup!\n\n"+
+				"Verify your email address to activate your account (valid for %v):\n%v/verify-email?token=%v\n",
+			config.Config.VerificationTTL,
+			config.Config.WebHost,
+			url.QueryEscape(token)),
+	})
+}
+
+// VerifyEmailHandler activates an account from a verification link.
+// Expected body:
+//   token
+func VerifyEmailHandler(res http.ResponseWriter, req *http.Request) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	err := users.NewStore(db).Verify(authConfig(), req.PostFormValue("token"))
+	jsonOut := json.NewEncoder(res)
+	if err == users.ErrInvalidVerificationToken {
+		res.WriteHeader(http.StatusBadRequest)
+		jsonOut.Encode(jsonErrorResponse{-1, "Invalid or expired verification token."})
+		return
+	} else if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		jsonOut.Encode(jsonErrorResponse{-2, "Unable to verify account."})
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerificationHandler sends a new verification link to an unverified account.
+// Like the password reset, the response does not reveal whether the email is registered.
+// Expected body:
+//   email
+func ResendVerificationHandler(res http.ResponseWriter, req *http.Request) {
+	email := req.PostFormValue("email")
+	background(func() { resendVerification(email) })
+	res.WriteHeader(http.StatusAccepted)
+}
+
+func resendVerification(email string) {
+	db, _ := database.GetDBResource()
+	defer db.Close()
+	user, err := users.NewStore(db).ByEmail(email)
+	if err == nil && !user.IsVerified {
+		err = sendVerificationEmail(user)
+	}
+	if err != nil && err != users.ErrUserNotFound {
+		log.Println(err)
+	}
+}
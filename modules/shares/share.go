@@ -0,0 +1,63 @@
+package shares
+
+import (
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+// Targets that can be shared.
+const (
+	TargetContainer = "container"
+	TargetLocation  = "location"
+)
+
+// Link is a revocable public link to a container or location.
+// The token is only available when the link is created; only its hash is stored.
+type Link struct {
+	ID          int64      `json:"id"`
+	HouseholdID int64      `json:"household_id"`
+	CreatedBy   int64      `json:"created_by"`
+	TargetType  string     `json:"target_type"`
+	TargetID    int64      `json:"target_id"`
+	Token       string     `json:"token,omitempty"`
+	Expires     *time.Time `json:"expires"`
+	IsRevoked   bool       `json:"is_revoked"`
+	Created     time.Time  `json:"created"`
+}
+
+// PublicLocation is a location without owner-only fields such as its address.
+type PublicLocation struct {
+	UUID           string `json:"uuid"`
+	Name           string `json:"name"`
+	ContainerCount int    `json:"container_count"`
+}
+
+// PublicContainer is a container without owner-only fields.
+type PublicContainer struct {
+	UUID               string          `json:"uuid"`
+	Name               string          `json:"name"`
+	ContainerItemCount int             `json:"container_item_count"`
+	Location           *PublicLocation `json:"location"`
+	Modified           time.Time       `json:"modified"`
+}
+
+// PublicItem is an item without owner-only fields.
+type PublicItem struct {
+	UUID     string `json:"uuid"`
+	Body     string `json:"body"`
+	Quantity int    `json:"quantity"`
+}
+
+// ContainerView is what a share link shows of a container.
+type ContainerView struct {
+	Container     PublicContainer      `json:"container"`
+	Items         []PublicItem         `json:"items"`
+	PagedResponse models.PagedResponse `json:"meta"`
+}
+
+// LocationView is what a share link shows of a location.
+type LocationView struct {
+	Location   PublicLocation    `json:"location"`
+	Containers []PublicContainer `json:"containers"`
+}
@@ -0,0 +1,229 @@
+package shares
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/containers"
+	"github.com/cjsaylor/boxmeup-go/modules/items"
+	"github.com/cjsaylor/boxmeup-go/modules/locations"
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+// QueryLimit is the maximum number of items per page of a shared container.
+const QueryLimit = 50
+
+var (
+	// ErrInvalidShare is returned when a share token is unknown, revoked or expired.
+	ErrInvalidShare = errors.New("invalid or expired share link")
+	// ErrShareNotFound is returned when a share link does not exist.
+	ErrShareNotFound = errors.New("share link not found")
+	// ErrNotShared is returned when requesting something the share link does not cover.
+	ErrNotShared = errors.New("not covered by this share link")
+)
+
+// Store persists share links and retrieves what they expose.
+type Store struct {
+	DB *sql.DB
+}
+
+// NewStore constructs a storage interface for share links.
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+const linkFields = "id, household_id, created_by, target_type, target_id, expires, is_revoked, created"
+
+func scanLink(row models.Scanner) (Link, error) {
+	var link Link
+	var expires mysql.NullTime
+	err := row.Scan(
+		&link.ID,
+		&link.HouseholdID,
+		&link.CreatedBy,
+		&link.TargetType,
+		&link.TargetID,
+		&expires,
+		&link.IsRevoked,
+		&link.Created)
+	if expires.Valid {
+		link.Expires = &expires.Time
+	}
+	return link, err
+}
+
+// Create mints a share link for a container or location. A zero ttl never expires.
+func (s *Store) Create(createdBy int64, householdID int64, targetType string, targetID int64, ttl time.Duration) (Link, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Link{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	var expires interface{}
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	q := `
+		insert into share_links (token_hash, household_id, created_by, target_type, target_id, expires, created)
+		values (?, ?, ?, ?, ?, ?, now())
+	`
+	res, err := s.DB.Exec(q, hashToken(token), householdID, createdBy, targetType, targetID, expires)
+	if err != nil {
+		return Link{}, err
+	}
+	ID, _ := res.LastInsertId()
+	link, err := scanLink(s.DB.QueryRow("select "+linkFields+" from share_links where id = ?", ID))
+	link.Token = token
+	return link, err
+}
+
+// Links lists the links of a container or location, including revoked and expired ones.
+func (s *Store) Links(targetType string, targetID int64) ([]Link, error) {
+	links := []Link{}
+	q := "select " + linkFields + " from share_links where target_type = ? and target_id = ? order by id desc"
+	rows, err := s.DB.Query(q, targetType, targetID)
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// ByID retrieves a share link.
+func (s *Store) ByID(ID int64) (Link, error) {
+	link, err := scanLink(s.DB.QueryRow("select "+linkFields+" from share_links where id = ?", ID))
+	if err == sql.ErrNoRows {
+		err = ErrShareNotFound
+	}
+	return link, err
+}
+
+// Revoke disables a share link.
+func (s *Store) Revoke(ID int64) error {
+	_, err := s.DB.Exec("update share_links set is_revoked = 1 where id = ?", ID)
+	return err
+}
+
+// Resolve retrieves the active link of a token.
+func (s *Store) Resolve(token string) (Link, error) {
+	q := `
+		select ` + linkFields + ` from share_links
+		where token_hash = ? and is_revoked = 0 and (expires is null or expires > ?)
+	`
+	link, err := scanLink(s.DB.QueryRow(q, hashToken(token), time.Now()))
+	if err == sql.ErrNoRows {
+		err = ErrInvalidShare
+	}
+	return link, err
+}
+
+func publicLocation(location locations.Location) *PublicLocation {
+	return &PublicLocation{
+		UUID:           location.UUID,
+		Name:           location.Name,
+		ContainerCount: location.ContainerCount,
+	}
+}
+
+func publicContainer(container containers.Container) PublicContainer {
+	public := PublicContainer{
+		UUID:               container.UUID,
+		Name:               container.Name,
+		ContainerItemCount: container.ContainerItemCount,
+		Modified:           container.Modified,
+	}
+	if container.Location != nil {
+		public.Location = publicLocation(*container.Location)
+	}
+	return public
+}
+
+// Container shows a shared container with a page of its items.
+// For location links the container is identified by UUID and must be in the shared location.
+func (s *Store) Container(link Link, containerUUID string, page int) (ContainerView, error) {
+	view := ContainerView{Items: []PublicItem{}}
+	containerID := link.TargetID
+	if link.TargetType == TargetLocation {
+		q := "select id from containers where uuid = ? and location_id = ? and household_id = ? and deleted_at is null"
+		err := s.DB.QueryRow(q, containerUUID, link.TargetID, link.HouseholdID).Scan(&containerID)
+		if err == sql.ErrNoRows {
+			return view, ErrNotShared
+		} else if err != nil {
+			return view, err
+		}
+	}
+	container, err := containers.NewStore(s.DB).ByID(containerID)
+	if err == sql.ErrNoRows || (err == nil && container.HouseholdID != link.HouseholdID) {
+		return view, ErrInvalidShare
+	} else if err != nil {
+		return view, err
+	}
+	if link.TargetType == TargetContainer && containerUUID != "" && containerUUID != container.UUID {
+		return view, ErrNotShared
+	}
+	view.Container = publicContainer(container)
+	var limit models.QueryLimit
+	limit.SetPage(page, QueryLimit)
+	itemModel := items.NewStore(s.DB)
+	response, err := itemModel.GetContainerItems(&container, itemModel.GetSortBy("body", models.ASC, 0), limit)
+	if err != nil {
+		return view, err
+	}
+	for _, item := range response.Items {
+		view.Items = append(view.Items, PublicItem{UUID: item.UUID, Body: item.Body, Quantity: item.Quantity})
+	}
+	view.PagedResponse = response.PagedResponse
+	return view, nil
+}
+
+// Location shows a shared location with the containers it holds.
+func (s *Store) Location(link Link) (LocationView, error) {
+	view := LocationView{Containers: []PublicContainer{}}
+	if link.TargetType != TargetLocation {
+		return view, ErrNotShared
+	}
+	location, err := locations.NewStore(s.DB).ByID(link.TargetID)
+	if err == sql.ErrNoRows || (err == nil && location.HouseholdID != link.HouseholdID) {
+		return view, ErrInvalidShare
+	} else if err != nil {
+		return view, err
+	}
+	view.Location = *publicLocation(location)
+	q := `
+		select uuid, name, container_item_count, modified
+		from containers
+		where location_id = ? and household_id = ? and deleted_at is null
+		order by name
+	`
+	rows, err := s.DB.Query(q, location.ID, link.HouseholdID)
+	if err != nil {
+		return view, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		container := PublicContainer{Location: &view.Location}
+		if err = rows.Scan(&container.UUID, &container.Name, &container.ContainerItemCount, &container.Modified); err != nil {
+			return view, err
+		}
+		view.Containers = append(view.Containers, container)
+	}
+	return view, rows.Err()
+}
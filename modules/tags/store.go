@@ -0,0 +1,185 @@
+package tags
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+)
+
+var (
+	// ErrTagExists is returned when a household already has a tag with the name.
+	ErrTagExists = errors.New("a tag with this name already exists")
+	// ErrTagNotFound is returned when a tag does not exist.
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+// Store persists the tag vocabulary of households and the tags of containers.
+type Store struct {
+	DB *sql.DB
+}
+
+// NewStore constructs a storage interface for tags.
+func NewStore(db *sql.DB) *Store {
+	return &Store{DB: db}
+}
+
+func in(count int) string {
+	return "(?" + strings.Repeat(",?", count-1) + ")"
+}
+
+// Create adds a tag to the vocabulary of a household.
+func (s *Store) Create(householdID int64, name string) (Tag, error) {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return Tag{}, err
+	}
+	q := "insert into tags (household_id, name, created, modified) values (?, ?, now(), now())"
+	res, err := s.DB.Exec(q, householdID, name)
+	if models.IsDuplicate(err) {
+		return Tag{}, ErrTagExists
+	} else if err != nil {
+		return Tag{}, err
+	}
+	ID, _ := res.LastInsertId()
+	return s.ByID(ID)
+}
+
+// ByID retrieves a tag.
+func (s *Store) ByID(ID int64) (Tag, error) {
+	var tag Tag
+	q := "select id, household_id, name, created, modified from tags where id = ?"
+	err := s.DB.QueryRow(q, ID).Scan(&tag.ID, &tag.HouseholdID, &tag.Name, &tag.Created, &tag.Modified)
+	if err == sql.ErrNoRows {
+		err = ErrTagNotFound
+	}
+	return tag, err
+}
+
+// Rename changes the name of a tag on every container it is attached to.
+func (s *Store) Rename(ID int64, name string) error {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec("update tags set name = ?, modified = now() where id = ?", name, ID)
+	if models.IsDuplicate(err) {
+		return ErrTagExists
+	}
+	return err
+}
+
+// Delete removes a tag from the vocabulary and from every container.
+func (s *Store) Delete(ID int64) error {
+	_, err := s.DB.Exec("delete from tags where id = ?", ID)
+	return err
+}
+
+// Usage lists the tags of a household by name along with how many containers use each.
+func (s *Store) Usage(householdID int64) ([]Usage, error) {
+	q := `
+		select t.id, t.household_id, t.name, t.created, t.modified, count(ct.container_id)
+		from tags t
+		left join container_tags ct on ct.tag_id = t.id
+			and ct.container_id in (select id from containers where deleted_at is null)
+		where t.household_id = ?
+		group by t.id
+		order by t.name
+	`
+	rows, err := s.DB.Query(q, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := []Usage{}
+	for rows.Next() {
+		var tag Usage
+		err = rows.Scan(&tag.ID, &tag.HouseholdID, &tag.Name, &tag.Created, &tag.Modified, &tag.ContainerCount)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, tag)
+	}
+	return usage, rows.Err()
+}
+
+// SetContainerTags replaces the tags of a container. Names missing from the household's
+// vocabulary are added to it.
+func (s *Store) SetContainerTags(containerID int64, householdID int64, names []string) (Tags, error) {
+	names, err := NormalizeNames(names)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec("delete from container_tags where container_id = ?", containerID)
+	if err == nil && len(names) > 0 {
+		args := make([]interface{}, 0, len(names)*2)
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = "(?, ?, now(), now())"
+			args = append(args, householdID, name)
+		}
+		q := "insert ignore into tags (household_id, name, created, modified) values " + strings.Join(values, ", ")
+		_, err = tx.Exec(q, args...)
+	}
+	if err == nil && len(names) > 0 {
+		q := `
+			insert into container_tags (container_id, tag_id, created)
+			select ?, id, now() from tags where household_id = ? and name in ` + in(len(names))
+		args := []interface{}{containerID, householdID}
+		for _, name := range names {
+			args = append(args, name)
+		}
+		_, err = tx.Exec(q, args...)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return nil, err
+	}
+	tagged, err := s.ContainerTags([]int64{containerID})
+	return tagged[containerID], err
+}
+
+// ContainerTags retrieves the tags of many containers at once, keyed by container ID.
+// Every requested container is present in the result.
+func (s *Store) ContainerTags(containerIDs []int64) (map[int64]Tags, error) {
+	tagged := make(map[int64]Tags, len(containerIDs))
+	if len(containerIDs) == 0 {
+		return tagged, nil
+	}
+	args := make([]interface{}, len(containerIDs))
+	for i, ID := range containerIDs {
+		tagged[ID] = Tags{}
+		args[i] = ID
+	}
+	q := `
+		select ct.container_id, t.id, t.household_id, t.name, t.created, t.modified
+		from container_tags ct
+		inner join tags t on t.id = ct.tag_id
+		where ct.container_id in ` + in(len(containerIDs)) + `
+		order by t.name
+	`
+	rows, err := s.DB.Query(q, args...)
+	if err != nil {
+		return tagged, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var containerID int64
+		var tag Tag
+		err = rows.Scan(&containerID, &tag.ID, &tag.HouseholdID, &tag.Name, &tag.Created, &tag.Modified)
+		if err != nil {
+			return tagged, err
+		}
+		tagged[containerID] = append(tagged[containerID], tag)
+	}
+	return tagged, rows.Err()
+}
@@ -0,0 +1,71 @@
+package tags
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the maximum number of characters in a tag name.
+const MaxNameLength = 40
+
+// ErrInvalidName is returned for empty or overly long tag names.
+var ErrInvalidName = errors.New("tag names must be between 1 and 40 characters")
+
+// Tag labels containers across locations, e.g. "christmas" or "fragile".
+type Tag struct {
+	ID          int64     `json:"id"`
+	HouseholdID int64     `json:"household_id"`
+	Name        string    `json:"name"`
+	Created     time.Time `json:"created"`
+	Modified    time.Time `json:"modified"`
+}
+
+// Usage is a tag along with the number of containers it is attached to.
+type Usage struct {
+	Tag
+	ContainerCount int `json:"container_count"`
+}
+
+// Tags is a group of tags
+type Tags []Tag
+
+// Names lists the names of the tags.
+func (t Tags) Names() []string {
+	names := make([]string, len(t))
+	for i, tag := range t {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// NormalizeName lower cases a tag name and collapses its whitespace
+// so "Christmas  Lights" and "christmas lights" are the same tag.
+func NormalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	return name, nil
+}
+
+// NormalizeNames normalizes a list of names, removing blanks and duplicates.
+func NormalizeNames(names []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		name, err := NormalizeName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
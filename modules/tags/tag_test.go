@@ -0,0 +1,31 @@
+package tags_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/tags"
+)
+
+func TestNormalizeName(t *testing.T) {
+	name, err := tags.NormalizeName("  Christmas \t Lights ")
+	if err != nil || name != "christmas lights" {
+		t.Errorf("Expected 'christmas lights' but got %q (%v)", name, err)
+	}
+	for _, invalid := range []string{"", "   ", strings.Repeat("a", tags.MaxNameLength+1)} {
+		if _, err = tags.NormalizeName(invalid); err != tags.ErrInvalidName {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestNormalizeNames(t *testing.T) {
+	names, err := tags.NormalizeNames([]string{"Fragile", "", "camping", "fragile "})
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(names, []string{"fragile", "camping"}) {
+		t.Errorf("Expected duplicates and blanks to be removed but got %v", names)
+	}
+}
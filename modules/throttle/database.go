@@ -0,0 +1,85 @@
+package throttle
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// DBStore keeps throttle state in the login_attempts table so it is shared between server instances.
+type DBStore struct {
+	DB *sql.DB
+}
+
+// NewDBStore constructs a database backed store.
+func NewDBStore(db *sql.DB) *DBStore {
+	return &DBStore{DB: db}
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func getState(db queryRower, key string) (State, error) {
+	var state State
+	var lockedUntil mysql.NullTime
+	q := `
+		select failures, locked_until from login_attempts
+		where throttle_key = ? and (expires > ? or locked_until > ?)
+	`
+	now := time.Now()
+	err := db.QueryRow(q, key, now, now).Scan(&state.Failures, &lockedUntil)
+	if err == sql.ErrNoRows {
+		return State{}, nil
+	}
+	state.LockedUntil = lockedUntil.Time
+	return state, err
+}
+
+// Get returns the current state of a key.
+func (d *DBStore) Get(key string) (State, error) {
+	return getState(d.DB, key)
+}
+
+// Increment records a failure for the key.
+func (d *DBStore) Increment(key string, ttl time.Duration) (State, error) {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return State{}, err
+	}
+	q := `
+		insert into login_attempts (throttle_key, failures, expires) values (?, 1, ?)
+		on duplicate key update
+			failures = if(expires > ? or locked_until > ?, failures + 1, 1),
+			expires = values(expires)
+	`
+	now := time.Now()
+	_, err = tx.Exec(q, key, now.Add(ttl), now, now)
+	if err != nil {
+		tx.Rollback()
+		return State{}, err
+	}
+	state, err := getState(tx, key)
+	if err != nil {
+		tx.Rollback()
+		return state, err
+	}
+	return state, tx.Commit()
+}
+
+// Lock blocks the key until the given time.
+func (d *DBStore) Lock(key string, until time.Time) error {
+	q := `
+		insert into login_attempts (throttle_key, failures, locked_until, expires) values (?, 0, ?, ?)
+		on duplicate key update locked_until = values(locked_until)
+	`
+	_, err := d.DB.Exec(q, key, until, until)
+	return err
+}
+
+// Reset clears the state of the key.
+func (d *DBStore) Reset(key string) error {
+	_, err := d.DB.Exec("delete from login_attempts where throttle_key = ?", key)
+	return err
+}
@@ -0,0 +1,89 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of keys after which expired entries are pruned.
+const sweepThreshold = 10000
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// MemoryStore keeps throttle state in process memory.
+// State is not shared between server instances and is lost on restart.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemoryStore constructs an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (m *MemoryStore) entry(key string, now time.Time) *memoryEntry {
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if now.After(entry.expires) && now.After(entry.state.LockedUntil) {
+		delete(m.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// Get returns the current state of a key.
+func (m *MemoryStore) Get(key string) (State, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if entry := m.entry(key, time.Now()); entry != nil {
+		return entry.state, nil
+	}
+	return State{}, nil
+}
+
+// Increment records a failure for the key.
+func (m *MemoryStore) Increment(key string, ttl time.Duration) (State, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	if len(m.entries) > sweepThreshold {
+		for k := range m.entries {
+			m.entry(k, now)
+		}
+	}
+	entry := m.entry(key, now)
+	if entry == nil {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+	entry.state.Failures++
+	entry.expires = now.Add(ttl)
+	return entry.state, nil
+}
+
+// Lock blocks the key until the given time.
+func (m *MemoryStore) Lock(key string, until time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry := m.entry(key, time.Now())
+	if entry == nil {
+		entry = &memoryEntry{expires: until}
+		m.entries[key] = entry
+	}
+	entry.state.LockedUntil = until
+	return nil
+}
+
+// Reset clears the state of the key.
+func (m *MemoryStore) Reset(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.entries, key)
+	return nil
+}
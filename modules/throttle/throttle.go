@@ -0,0 +1,95 @@
+package throttle
+
+import (
+	"math"
+	"time"
+)
+
+// State is the failure history of a throttled key.
+type State struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// Store persists throttle state. Implementations must make Increment atomic.
+type Store interface {
+	// Get returns the current state of a key. Unknown or expired keys have a zero state.
+	Get(key string) (State, error)
+	// Increment records a failure and returns the new state.
+	// The state expires once the key has seen no failures for ttl.
+	Increment(key string, ttl time.Duration) (State, error)
+	// Lock blocks the key until the given time.
+	Lock(key string, until time.Time) error
+	// Reset clears the state of a key.
+	Reset(key string) error
+}
+
+// Policy describes how failures are penalized.
+type Policy struct {
+	// FreeAttempts is the number of failures allowed before any delay is enforced.
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure beyond FreeAttempts. It doubles with each failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential delay.
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures that triggers a lockout.
+	LockoutThreshold int
+	// LockoutDuration is how long a lockout lasts.
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered without new failures.
+	Window time.Duration
+}
+
+// Limiter applies a policy to keys kept in a store.
+type Limiter struct {
+	Store  Store
+	Policy Policy
+	now    func() time.Time
+}
+
+// NewLimiter constructs a limiter for a store and policy.
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{Store: store, Policy: policy, now: time.Now}
+}
+
+// Check reports how long the key must wait before another attempt. Zero means it may proceed.
+func (l *Limiter) Check(key string) (time.Duration, error) {
+	state, err := l.Store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if wait := state.LockedUntil.Sub(l.now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// Failure records a failed attempt and applies backoff or a lockout.
+// lockedOut is true when this failure triggered a lockout.
+func (l *Limiter) Failure(key string) (state State, lockedOut bool, err error) {
+	state, err = l.Store.Increment(key, l.Policy.Window)
+	if err != nil {
+		return state, false, err
+	}
+	var delay time.Duration
+	switch {
+	case l.Policy.LockoutThreshold > 0 && state.Failures >= l.Policy.LockoutThreshold:
+		delay = l.Policy.LockoutDuration
+		lockedOut = state.Failures == l.Policy.LockoutThreshold
+	case state.Failures > l.Policy.FreeAttempts:
+		exponent := float64(state.Failures - l.Policy.FreeAttempts - 1)
+		delay = time.Duration(float64(l.Policy.BaseDelay) * math.Pow(2, exponent))
+		if delay > l.Policy.MaxDelay || delay <= 0 {
+			delay = l.Policy.MaxDelay
+		}
+	default:
+		return state, false, nil
+	}
+	state.LockedUntil = l.now().Add(delay)
+	return state, lockedOut, l.Store.Lock(key, state.LockedUntil)
+}
+
+// Success clears the failure history of a key.
+func (l *Limiter) Success(key string) error {
+	return l.Store.Reset(key)
+}
@@ -0,0 +1,84 @@
+package throttle_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/throttle"
+)
+
+var policy = throttle.Policy{
+	FreeAttempts:     2,
+	BaseDelay:        time.Minute,
+	MaxDelay:         4 * time.Minute,
+	LockoutThreshold: 6,
+	LockoutDuration:  time.Hour,
+	Window:           time.Hour,
+}
+
+func TestFreeAttempts(t *testing.T) {
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), policy)
+	for i := 0; i < policy.FreeAttempts; i++ {
+		if _, lockedOut, err := limiter.Failure("key"); err != nil || lockedOut {
+			t.Errorf("Unexpected lockout or error on attempt %d: %v", i+1, err)
+		}
+	}
+	if wait, _ := limiter.Check("key"); wait != 0 {
+		t.Errorf("Expected no delay within free attempts, got %v", wait)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), policy)
+	expected := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, delay := range expected {
+		limiter.Failure("key")
+		wait, err := limiter.Check("key")
+		if err != nil {
+			t.Error(err)
+		}
+		if wait > delay || (delay > 0 && wait < delay-time.Second) {
+			t.Errorf("Attempt %d: expected a delay of %v, got %v", i+1, delay, wait)
+		}
+	}
+}
+
+func TestLockout(t *testing.T) {
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), policy)
+	for i := 1; i <= policy.LockoutThreshold+1; i++ {
+		_, lockedOut, _ := limiter.Failure("key")
+		if lockedOut != (i == policy.LockoutThreshold) {
+			t.Errorf("Attempt %d: unexpected lockout state %v", i, lockedOut)
+		}
+	}
+	if wait, _ := limiter.Check("key"); wait < policy.LockoutDuration-time.Second {
+		t.Errorf("Expected a lockout of %v, got %v", policy.LockoutDuration, wait)
+	}
+	if wait, _ := limiter.Check("other"); wait != 0 {
+		t.Error("Expected other keys to be unaffected")
+	}
+}
+
+func TestSuccessResets(t *testing.T) {
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), policy)
+	for i := 0; i < policy.LockoutThreshold; i++ {
+		limiter.Failure("key")
+	}
+	limiter.Success("key")
+	if wait, _ := limiter.Check("key"); wait != 0 {
+		t.Errorf("Expected success to clear the lockout, got %v", wait)
+	}
+	state, _, _ := limiter.Failure("key")
+	if state.Failures != 1 {
+		t.Errorf("Expected failures to restart at 1, got %d", state.Failures)
+	}
+}
+
+func TestWindowExpiry(t *testing.T) {
+	store := throttle.NewMemoryStore()
+	store.Increment("key", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if state, _ := store.Get("key"); state.Failures != 0 {
+		t.Errorf("Expected failures to expire, got %d", state.Failures)
+	}
+}
@@ -0,0 +1,183 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when an API key does not exist or does not belong to the user.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidSignature is returned when a signed API request can not be verified.
+	ErrInvalidSignature = errors.New("invalid request signature")
+	// ErrRequestExpired is returned when a signed API request timestamp is outside the allowed window.
+	ErrRequestExpired = errors.New("request timestamp outside of allowed window")
+	// ErrRequestReplayed is returned when a signed API request nonce has already been seen.
+	ErrRequestReplayed = errors.New("request has already been processed")
+)
+
+// APIKey is a long lived credential used to sign API requests.
+type APIKey struct {
+	ID       int64      `json:"id"`
+	UserID   int64      `json:"-"`
+	Name     string     `json:"name"`
+	Key      string     `json:"api_key"`
+	Secret   string     `json:"secret_key,omitempty"`
+	IsActive bool       `json:"is_active"`
+	LastUsed *time.Time `json:"last_used"`
+	Created  time.Time  `json:"created"`
+	Modified time.Time  `json:"modified"`
+}
+
+// SignedRequest contains the parts of an HTTP request covered by an API key signature.
+type SignedRequest struct {
+	KeyID      string
+	Timestamp  string
+	Nonce      string
+	Signature  string
+	Method     string
+	RequestURI string
+	Body       []byte
+}
+
+// SignAPIRequest calculates the hex encoded HMAC-SHA256 signature of a request.
+// The signed string is the method, request URI, timestamp, nonce and hex SHA256 body digest
+// separated by newlines.
+func SignAPIRequest(secret string, method string, requestURI string, timestamp string, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%v\n%v\n%v\n%v\n%x", method, requestURI, timestamp, nonce, digest)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateAPIKey generates a new API key for the user.
+// The secret is only available on the returned key and can not be retrieved again.
+func (s *Store) CreateAPIKey(userID int64, name string) (APIKey, error) {
+	key := APIKey{UserID: userID, Name: name, IsActive: true}
+	var err error
+	if key.Key, err = randomHex(20); err != nil {
+		return key, err
+	}
+	if key.Secret, err = randomHex(20); err != nil {
+		return key, err
+	}
+	q := `
+		insert into api_users (user_id, name, api_key, secret_key, is_active, created, modified)
+		values (?, ?, ?, ?, 1, now(), now())
+	`
+	res, err := s.DB.Exec(q, userID, name, key.Key, key.Secret)
+	if err != nil {
+		return key, err
+	}
+	key.ID, _ = res.LastInsertId()
+	key.Created = time.Now()
+	key.Modified = key.Created
+	return key, nil
+}
+
+// APIKeys lists all API keys of a user without their secrets.
+func (s *Store) APIKeys(userID int64) ([]APIKey, error) {
+	q := `
+		select id, user_id, name, api_key, is_active, last_used, created, modified
+		from api_users
+		where user_id = ?
+		order by created desc
+	`
+	rows, err := s.DB.Query(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []APIKey{}
+	for rows.Next() {
+		key := APIKey{}
+		var lastUsed mysql.NullTime
+		err = rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Key, &key.IsActive, &lastUsed, &key.Created, &key.Modified)
+		if err != nil {
+			return keys, err
+		}
+		if lastUsed.Valid {
+			key.LastUsed = &lastUsed.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey deactivates an API key belonging to the user.
+func (s *Store) RevokeAPIKey(userID int64, ID int64) error {
+	q := "update api_users set is_active = 0, modified = now() where id = ? and user_id = ?"
+	res, err := s.DB.Exec(q, ID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// VerifyAPIRequest authenticates a signed request and resolves the key owner.
+// Requests must be signed within the window of the current time and each nonce may only be used once.
+func (s *Store) VerifyAPIRequest(request SignedRequest, window time.Duration) (User, APIKey, error) {
+	var user User
+	var key APIKey
+	timestamp, err := strconv.ParseInt(request.Timestamp, 10, 64)
+	if err != nil {
+		return user, key, ErrInvalidSignature
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > window || skew < -window {
+		return user, key, ErrRequestExpired
+	}
+	if request.Nonce == "" || len(request.Nonce) > 64 {
+		return user, key, ErrInvalidSignature
+	}
+	q := `
+		select a.id, a.user_id, a.secret_key, u.uuid
+		from api_users a
+		inner join users u on u.id = a.user_id
+		where a.api_key = ? and a.is_active = 1
+	`
+	err = s.DB.QueryRow(q, request.KeyID).Scan(&key.ID, &key.UserID, &key.Secret, &user.UUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrInvalidSignature
+		}
+		return user, key, err
+	}
+	expected := SignAPIRequest(key.Secret, request.Method, request.RequestURI, request.Timestamp, request.Nonce, request.Body)
+	if !hmac.Equal([]byte(expected), []byte(request.Signature)) {
+		return user, key, ErrInvalidSignature
+	}
+	s.DB.Exec("delete from api_nonces where expires < ?", time.Now())
+	q = "insert into api_nonces (api_user_id, nonce, expires) values (?, ?, ?)"
+	_, err = s.DB.Exec(q, key.ID, request.Nonce, time.Unix(timestamp, 0).Add(window))
+	if err != nil {
+		if models.IsDuplicate(err) {
+			err = ErrRequestReplayed
+		}
+		return user, key, err
+	}
+	s.DB.Exec("update api_users set last_used = now() where id = ?", key.ID)
+	key.Secret = ""
+	user.ID = key.UserID
+	return user, key, nil
+}
@@ -0,0 +1,26 @@
+package users_test
+
+import (
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+func TestSignAPIRequest(t *testing.T) {
+	signature := users.SignAPIRequest("secret", "POST", "/api/container", "1500000000", "abc", []byte("name=Tote"))
+	if len(signature) != 64 {
+		t.Errorf("Expected hex encoded SHA256 signature but got %v", signature)
+	}
+	same := users.SignAPIRequest("secret", "POST", "/api/container", "1500000000", "abc", []byte("name=Tote"))
+	if same != signature {
+		t.Error("Expected signature to be deterministic.")
+	}
+	tampered := users.SignAPIRequest("secret", "POST", "/api/container", "1500000000", "abc", []byte("name=Bin"))
+	if tampered == signature {
+		t.Error("Expected signature to cover the request body.")
+	}
+	otherNonce := users.SignAPIRequest("secret", "POST", "/api/container", "1500000000", "abd", []byte("name=Tote"))
+	if otherNonce == signature {
+		t.Error("Expected signature to cover the nonce.")
+	}
+}
@@ -0,0 +1,251 @@
+package users
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// ErrUnknownKey is returned when a token references a key that is not in the key set.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// SigningKey is an asymmetric key used to sign or verify access tokens.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Private is only set for keys that can sign.
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet holds the active signing key and every key that is still accepted for verification.
+// Rotating keys means promoting a new active key while keeping the previous one for verification
+// until tokens signed with it have expired.
+type KeySet struct {
+	Active *SigningKey
+	Keys   map[string]*SigningKey
+}
+
+// JWK is a JSON Web Key (RFC 7517) describing a public verification key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the active private key and any additional verification keys from PEM files.
+func LoadKeySet(activePath string, verificationPaths []string) (*KeySet, error) {
+	set := &KeySet{Keys: make(map[string]*SigningKey)}
+	active, err := loadSigningKey(activePath)
+	if err != nil {
+		return nil, err
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("%v: active signing key must be a private key", activePath)
+	}
+	set.Active = active
+	set.Keys[active.ID] = active
+	for _, path := range verificationPaths {
+		if path == "" {
+			continue
+		}
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		set.Keys[key.ID] = key
+	}
+	return set, nil
+}
+
+func loadSigningKey(path string) (*SigningKey, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseSigningKey(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParseSigningKey parses a PEM encoded RSA or ECDSA private or public key.
+func ParseSigningKey(contents []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	key := &SigningKey{}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %v", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Private, key.Public = k, &k.PublicKey
+	case *ecdsa.PrivateKey:
+		key.Private, key.Public = k, &k.PublicKey
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		key.Public = k
+	default:
+		return nil, errors.New("only RSA and ECDSA keys are supported")
+	}
+	jwk, err := publicJWK(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	key.Method = jwt.GetSigningMethod(jwk.Alg)
+	key.ID, err = thumbprint(jwk)
+	return key, err
+}
+
+func encodeBigInt(i *big.Int, size int) string {
+	b := i.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func publicJWK(public crypto.PublicKey) (JWK, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   encodeBigInt(k.N, 0),
+			E:   encodeBigInt(big.NewInt(int64(k.E)), 0),
+		}, nil
+	case *ecdsa.PublicKey:
+		jwk := JWK{Kty: "EC", Use: "sig"}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		switch k.Curve {
+		case elliptic.P256():
+			jwk.Crv, jwk.Alg = "P-256", jwt.SigningMethodES256.Alg()
+		case elliptic.P384():
+			jwk.Crv, jwk.Alg = "P-384", jwt.SigningMethodES384.Alg()
+		case elliptic.P521():
+			jwk.Crv, jwk.Alg = "P-521", jwt.SigningMethodES512.Alg()
+		default:
+			return jwk, errors.New("unsupported elliptic curve")
+		}
+		jwk.X = encodeBigInt(k.X, size)
+		jwk.Y = encodeBigInt(k.Y, size)
+		return jwk, nil
+	}
+	return JWK{}, errors.New("only RSA and ECDSA keys are supported")
+}
+
+// thumbprint calculates the RFC 7638 JWK thumbprint used as the key ID.
+func thumbprint(jwk JWK) (string, error) {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	}
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// JWKS publishes the public half of every key in the set.
+func (k *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if k == nil {
+		return set
+	}
+	for ID, key := range k.Keys {
+		jwk, err := publicJWK(key.Public)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = ID
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// sign signs the claims with the active key, falling back to HS256 with the shared secret.
+func (config AuthConfig) sign(claims jwt.MapClaims) (string, error) {
+	if config.Keys == nil || config.Keys.Active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.JWTSecret))
+	}
+	token := jwt.NewWithClaims(config.Keys.Active.Method, claims)
+	token.Header["kid"] = config.Keys.Active.ID
+	return token.SignedString(config.Keys.Active.Private)
+}
+
+// verificationKey resolves the key a token must be verified with.
+// The key type must match the signing method to prevent algorithm confusion.
+func (config AuthConfig) verificationKey(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return []byte(config.JWTSecret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		kid, _ := token.Header["kid"].(string)
+		if config.Keys == nil {
+			return nil, ErrUnknownKey
+		}
+		key, ok := config.Keys.Keys[kid]
+		if !ok || key.Method.Alg() != token.Method.Alg() {
+			return nil, ErrUnknownKey
+		}
+		return key.Public, nil
+	}
+	return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+}
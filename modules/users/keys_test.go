@@ -0,0 +1,70 @@
+package users_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func newECKey(t *testing.T) *users.SigningKey {
+	private, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalECPrivateKey(private)
+	key, err := users.ParseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseSigningKey(t *testing.T) {
+	key := newECKey(t)
+	if key.Method != jwt.SigningMethodES256 {
+		t.Errorf("Expected ES256 but got %v", key.Method.Alg())
+	}
+	if key.ID == "" || key.Private == nil {
+		t.Error("Expected key ID and private key to be set.")
+	}
+}
+
+func TestValidateAndDecodeAuthClaim_KeySet(t *testing.T) {
+	active := newECKey(t)
+	retired := newECKey(t)
+	config := users.AuthConfig{
+		JWTSecret: "secret",
+		Keys: &users.KeySet{
+			Active: active,
+			Keys:   map[string]*users.SigningKey{active.ID: active},
+		},
+	}
+	sign := func(key *users.SigningKey) string {
+		token := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+			"typ": "access",
+			"id":  1,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = key.ID
+		signed, _ := token.SignedString(key.Private)
+		return signed
+	}
+	if _, err := users.ValidateAndDecodeAuthClaim(sign(active), config); err != nil {
+		t.Errorf("Expected token signed by the active key to validate but got %v", err)
+	}
+	if _, err := users.ValidateAndDecodeAuthClaim(sign(retired), config); err == nil {
+		t.Error("Expected token signed by an unknown key to fail.")
+	}
+	config.Keys.Keys[retired.ID] = retired
+	if _, err := users.ValidateAndDecodeAuthClaim(sign(retired), config); err != nil {
+		t.Errorf("Expected token signed by a verification key to validate but got %v", err)
+	}
+	jwks := config.Keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "EC" || jwks.Keys[0].Crv != "P-256" {
+		t.Errorf("Unexpected JWKS %+v", jwks)
+	}
+}
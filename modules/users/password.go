@@ -0,0 +1,108 @@
+package users
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordMismatch is returned when a password does not match its stored hash.
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher produces and verifies stored password hashes.
+// Hashes are stored as "<algorithm>$<hash>" so the algorithm can change over time.
+type PasswordHasher interface {
+	// Algorithm is the prefix used to identify hashes produced by this hasher.
+	Algorithm() string
+	// Hash produces a prefixed hash of the password.
+	Hash(password string) (string, error)
+	// Compare verifies the password against a prefixed hash.
+	Compare(hash string, password string) error
+	// NeedsRehash reports if a prefixed hash should be regenerated (ie: cost has changed).
+	NeedsRehash(hash string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher constructs a bcrypt hasher with the default cost.
+func NewBcryptHasher() BcryptHasher {
+	return BcryptHasher{Cost: bcrypt.DefaultCost}
+}
+
+// Algorithm is the stored hash prefix for bcrypt.
+func (h BcryptHasher) Algorithm() string {
+	return "bcrypt"
+}
+
+// Hash produces a prefixed bcrypt hash of the password.
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v$%s", h.Algorithm(), hashed), nil
+}
+
+// Compare verifies the password against a prefixed bcrypt hash.
+func (h BcryptHasher) Compare(hash string, password string) error {
+	algorithm, hashed := splitPasswordHash(hash)
+	if algorithm != h.Algorithm() {
+		return ErrPasswordMismatch
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) != nil {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports if the hash was generated with a different cost.
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	algorithm, hashed := splitPasswordHash(hash)
+	if algorithm != h.Algorithm() {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != h.Cost
+}
+
+func splitPasswordHash(hash string) (algorithm string, hashed string) {
+	parts := strings.SplitN(hash, "$", 2)
+	if len(parts) != 2 {
+		return "", hash
+	}
+	return parts[0], parts[1]
+}
+
+// legacyHashPassword is the original (unprefixed) salted SHA1 scheme.
+// It is only used to verify accounts that have not logged in since the migration.
+func legacyHashPassword(config AuthConfig, password string) string {
+	data := []byte(fmt.Sprintf("%v%v", config.LegacySalt, password))
+	return fmt.Sprintf("%x", sha1.Sum(data))
+}
+
+func isLegacyPasswordHash(hash string) bool {
+	return len(hash) == sha1.Size*2 && !strings.Contains(hash, "$")
+}
+
+// verifyPassword checks a password against either a legacy or a prefixed hash.
+// needsRehash is true when the stored hash should be replaced on a successful match.
+func verifyPassword(hasher PasswordHasher, config AuthConfig, hash string, password string) (needsRehash bool, err error) {
+	if isLegacyPasswordHash(hash) {
+		expected := legacyHashPassword(config, password)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(hash)) != 1 {
+			return false, ErrPasswordMismatch
+		}
+		return true, nil
+	}
+	if err = hasher.Compare(hash, password); err != nil {
+		return false, err
+	}
+	return hasher.NeedsRehash(hash), nil
+}
@@ -0,0 +1,42 @@
+package users_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasher_Hash(t *testing.T) {
+	hasher := users.BcryptHasher{Cost: bcrypt.MinCost}
+	hash, err := hasher.Hash("test1234")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.HasPrefix(hash, "bcrypt$") {
+		t.Errorf("Expected hash to be prefixed with the algorithm but got %v", hash)
+	}
+	if err = hasher.Compare(hash, "test1234"); err != nil {
+		t.Error(err)
+	}
+	if err = hasher.Compare(hash, "wrong"); err != users.ErrPasswordMismatch {
+		t.Errorf("Expected password mismatch but got %v", err)
+	}
+}
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	hasher := users.BcryptHasher{Cost: bcrypt.MinCost}
+	hash, _ := hasher.Hash("test1234")
+	if hasher.NeedsRehash(hash) {
+		t.Error("Expected hash with the same cost to not need a rehash.")
+	}
+	stronger := users.BcryptHasher{Cost: bcrypt.MinCost + 1}
+	if !stronger.NeedsRehash(hash) {
+		t.Error("Expected hash with a lower cost to need a rehash.")
+	}
+	if !hasher.NeedsRehash("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8") {
+		t.Error("Expected legacy hash to need a rehash.")
+	}
+}
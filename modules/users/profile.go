@@ -0,0 +1,204 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrEmailTaken is returned when an email address belongs to another user.
+	ErrEmailTaken = errors.New("user already exists with given email")
+	// ErrInvalidEmail is returned when an email address is blank or malformed.
+	ErrInvalidEmail = errors.New("invalid email address")
+)
+
+// ProfileUpdate contains changes to a user's account.
+// Empty fields are left unchanged. Any change requires the current password.
+type ProfileUpdate struct {
+	Email           string
+	Password        string
+	CurrentPassword string
+}
+
+// reauthenticate verifies the user's current password.
+func (s *Store) reauthenticate(config AuthConfig, userID int64, password string) error {
+	var storedHash string
+	err := s.DB.QueryRow("select password from users where id = ?", userID).Scan(&storedHash)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+	_, err = verifyPassword(s.Hasher, config, storedHash, password)
+	return err
+}
+
+// UpdateProfile changes the email address and/or password of a user.
+// A password change revokes all of the user's sessions and starts a new one, which is returned.
+func (s *Store) UpdateProfile(config AuthConfig, userID int64, update ProfileUpdate) (*TokenPair, error) {
+	update.Email = strings.TrimSpace(update.Email)
+	if update.Email == "" && update.Password == "" {
+		return nil, nil
+	}
+	if update.Email != "" && !strings.Contains(update.Email, "@") {
+		return nil, ErrInvalidEmail
+	}
+	if err := s.reauthenticate(config, userID, update.CurrentPassword); err != nil {
+		return nil, err
+	}
+	var hashedPassword string
+	var err error
+	if update.Password != "" {
+		if hashedPassword, err = s.Hasher.Hash(update.Password); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if update.Email != "" {
+		var count int
+		q := "select count(*) from users where email = ? and id != ?"
+		err = tx.QueryRow(q, update.Email, userID).Scan(&count)
+		if err == nil && count > 0 {
+			err = ErrEmailTaken
+		}
+		if err == nil {
+			_, err = tx.Exec("update users set email = ?, modified = now() where id = ?", update.Email, userID)
+		}
+	}
+	if err == nil && hashedPassword != "" {
+		q := "update users set password = ?, reset_password = 0, modified = now() where id = ?"
+		if _, err = tx.Exec(q, hashedPassword, userID); err == nil {
+			err = RevokeUserSessions(tx, userID)
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil || hashedPassword == "" {
+		return nil, err
+	}
+	user, err := s.ByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.startSession(config, user)
+	return &tokens, err
+}
+
+// DeleteAccount permanently removes a user along with all of their locations, containers and items.
+// Households the user shares with others are kept: another member is promoted if the user was the
+// only owner, and the inventory the user created there is handed over to an owner.
+// Everything is removed in a single transaction so a failure leaves the account intact.
+func (s *Store) DeleteAccount(config AuthConfig, userID int64, currentPassword string) error {
+	if err := s.reauthenticate(config, userID, currentPassword); err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err = deleteAccount(tx, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteAccount(tx *sql.Tx, userID int64) error {
+	q := `
+		select household_id, count(*) = 1,
+			sum(role = 'owner' and user_id != ?) = 0
+		from household_members
+		where household_id in (select household_id from household_members where user_id = ?)
+		group by household_id
+	`
+	rows, err := tx.Query(q, userID, userID)
+	if err != nil {
+		return err
+	}
+	var sole, orphaned []interface{}
+	for rows.Next() {
+		var householdID int64
+		var isSole, isOrphaned bool
+		if err = rows.Scan(&householdID, &isSole, &isOrphaned); err != nil {
+			rows.Close()
+			return err
+		}
+		if isSole {
+			sole = append(sole, householdID)
+		} else if isOrphaned {
+			orphaned = append(orphaned, householdID)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, householdID := range orphaned {
+		q = `
+			update household_members set role = 'owner'
+			where household_id = ? and user_id != ?
+			order by role = 'editor' desc, created
+			limit 1
+		`
+		if _, err = tx.Exec(q, householdID, userID); err != nil {
+			return err
+		}
+	}
+	// Sole households are matched with a placeholder so the list is never empty.
+	soleIn := "(0" + strings.Repeat(",?", len(sole)) + ")"
+	soleArgs := append(append([]interface{}{}, sole...), userID)
+	type statement struct {
+		query string
+		args  []interface{}
+	}
+	statements := []statement{
+		{
+			`delete ci from container_items ci
+				inner join containers c on c.id = ci.container_id
+				where c.household_id in ` + soleIn + ` or (c.user_id = ? and c.household_id = 0)`,
+			soleArgs,
+		},
+		{"delete from containers where household_id in " + soleIn + " or (user_id = ? and household_id = 0)", soleArgs},
+		{"delete from locations where household_id in " + soleIn + " or (user_id = ? and household_id = 0)", soleArgs},
+		{"delete from households where id in " + soleIn, sole},
+	}
+	for _, table := range []string{"containers", "locations"} {
+		statements = append(statements, statement{
+			`update ` + table + ` t set t.user_id = (
+				select m.user_id from household_members m
+				where m.household_id = t.household_id and m.user_id != ? and m.role = 'owner'
+				order by m.created
+				limit 1
+			)
+			where t.user_id = ?`,
+			[]interface{}{userID, userID},
+		})
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	for _, q = range []string{
+		"delete from household_members where user_id = ?",
+		"delete n from api_nonces n inner join api_users a on a.id = n.api_user_id where a.user_id = ?",
+		"delete from api_users where user_id = ?",
+		"delete from refresh_tokens where user_id = ?",
+		"delete from password_resets where user_id = ?",
+		"delete from recovery_codes where user_id = ?",
+		"delete from security_events where user_id = ?",
+		"delete from users where id = ?",
+	} {
+		if _, err = tx.Exec(q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
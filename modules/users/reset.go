@@ -0,0 +1,104 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the request.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidResetToken is returned when a reset token is unknown, used or expired.
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	// ErrEmptyPassword is returned when attempting to set a blank password.
+	ErrEmptyPassword = errors.New("password must not be empty")
+)
+
+// RequestPasswordReset flags the user for a password reset and generates a single use token.
+// The token is only returned to the caller; only its hash is persisted.
+func (s *Store) RequestPasswordReset(email string, ttl time.Duration) (User, string, error) {
+	var user User
+	err := s.DB.QueryRow("select id, email from users where email = ?", email).Scan(&user.ID, &user.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrUserNotFound
+		}
+		return user, "", err
+	}
+	token, err := randomToken(32)
+	if err != nil {
+		return user, "", err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return user, "", err
+	}
+	q := `
+		insert into password_resets (user_id, token_hash, expires, created)
+		values (?, ?, ?, now())
+	`
+	_, err = tx.Exec(q, user.ID, hashToken(token), time.Now().Add(ttl))
+	if err == nil {
+		_, err = tx.Exec("update users set reset_password = 1, modified = now() where id = ?", user.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	user.ResetPassword = err == nil
+	return user, token, err
+}
+
+// ResetPassword consumes a reset token and sets the user's new password.
+// All outstanding reset tokens and sessions of the user are revoked.
+func (s *Store) ResetPassword(token string, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	var ID, userID int64
+	q := `
+		select id, user_id from password_resets
+		where token_hash = ? and is_used = 0 and expires > ?
+		for update
+	`
+	// Expiry is written from Go, so compare it with Go's clock rather than the session time zone of MySQL.
+	err = tx.QueryRow(q, hashToken(token), time.Now()).Scan(&ID, &userID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"update password_resets set is_used = 1 where user_id = ?", []interface{}{userID}},
+		{
+			"update users set password = ?, reset_password = 0, modified = now() where id = ?",
+			[]interface{}{hashedPassword, userID},
+		},
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement.query, statement.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err = RevokeUserSessions(tx, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,64 @@
+package users
+
+import (
+	"time"
+)
+
+// Security events recorded against a user's account.
+const (
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventTwoFactorFailed = "2fa_failed"
+	SecurityEventAccountLocked   = "account_locked"
+)
+
+// securityEventLimit is the number of most recent events returned to the user.
+const securityEventLimit = 100
+
+// SecurityEvent records suspicious activity on an account.
+type SecurityEvent struct {
+	ID        int64     `json:"id"`
+	Event     string    `json:"event"`
+	IPAddress string    `json:"ip_address"`
+	Created   time.Time `json:"created"`
+}
+
+// RecordSecurityEvent stores an event against the user's account.
+func (s *Store) RecordSecurityEvent(userID int64, event string, ipAddress string) error {
+	q := "insert into security_events (user_id, event, ip_address, created) values (?, ?, ?, now())"
+	_, err := s.DB.Exec(q, userID, event, ipAddress)
+	return err
+}
+
+// SecurityEvents retrieves the most recent events of a user, newest first.
+func (s *Store) SecurityEvents(userID int64) ([]SecurityEvent, error) {
+	events := []SecurityEvent{}
+	q := `
+		select id, event, ip_address, created from security_events
+		where user_id = ?
+		order by id desc
+		limit ?
+	`
+	rows, err := s.DB.Query(q, userID, securityEventLimit)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event := SecurityEvent{}
+		if err = rows.Scan(&event.ID, &event.Event, &event.IPAddress, &event.Created); err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ChallengeUserID identifies the user a two factor challenge was issued to.
+func ChallengeUserID(config AuthConfig, challenge string) (int64, error) {
+	claims, err := parseToken(challenge, config)
+	if err != nil || claims["typ"] != tokenTypeTwoFactorChallenge {
+		return 0, ErrInvalidChallenge
+	}
+	userID, _ := claims["id"].(float64)
+	return int64(userID), nil
+}
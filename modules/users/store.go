@@ -1,9 +1,7 @@
 package users
 
 import (
-	"crypto/sha1"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -13,66 +11,98 @@ import (
 
 // Store is a persistence structure to get and store users.
 type Store struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Hasher PasswordHasher
 }
 
 // NewStore constructs a storage interface for users.
 func NewStore(db *sql.DB) *Store {
-	return &Store{DB: db}
+	return &Store{DB: db, Hasher: NewBcryptHasher()}
 }
 
 // AuthConfig is configuration used for authorization operations
 type AuthConfig struct {
-	LegacySalt string
-	JWTSecret  string
+	LegacySalt      string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// Keys signs access tokens asymmetrically when set. Otherwise JWTSecret is used (HS256).
+	Keys *KeySet
 }
 
-func hashPassword(config AuthConfig, password string) string {
-	data := []byte(fmt.Sprintf("%v%v", config.LegacySalt, password))
-	return fmt.Sprintf("%x", sha1.Sum(data))
-}
-
-// Login authenticates user credentials and produces a signed JWT
-func (s *Store) Login(config AuthConfig, email string, password string) (string, error) {
-	hashedPassword := hashPassword(config, password)
-	var ID int
-	var UUID string
+// Login authenticates user credentials and starts a new session with a signed JWT and refresh token.
+// Users still on a legacy or outdated password hash are rehashed on success.
+// Unverified and deactivated accounts are refused with ErrAccountNotVerified and ErrAccountDeactivated.
+// Users with two factor authentication enabled receive a *TwoFactorChallenge error instead of a session.
+// Unknown emails return sql.ErrNoRows and wrong passwords ErrPasswordMismatch.
+func (s *Store) Login(config AuthConfig, email string, password string) (TokenPair, error) {
+	var user User
+	var twoFactorEnabled bool
 	q := `
-		select id, uuid from users where email = ? and password = ?
+		select id, uuid, password, is_active, is_verified, is_admin, totp_enabled from users where email = ?
 	`
-	err := s.DB.QueryRow(q, email, hashedPassword).Scan(&ID, &UUID)
+	err := s.DB.QueryRow(q, email).Scan(
+		&user.ID,
+		&user.UUID,
+		&user.Password,
+		&user.IsActive,
+		&user.IsVerified,
+		&user.IsAdmin,
+		&twoFactorEnabled)
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Fatal(err)
+		return TokenPair{}, err
+	}
+	needsRehash, err := verifyPassword(s.Hasher, config, user.Password, password)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err = accountStatusError(user.IsActive, user.IsVerified); err != nil {
+		return TokenPair{}, err
+	}
+	if needsRehash {
+		if err = s.updatePassword(user.ID, password); err != nil {
+			return TokenPair{}, err
 		}
-		return "", err
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":   ID,
-		"uuid": UUID,
-		"nbf":  time.Now().Unix(),
-		"exp":  time.Now().AddDate(0, 0, 5).Unix(),
-	})
-	return token.SignedString([]byte(config.JWTSecret))
+	if twoFactorEnabled {
+		challenge, err := challengeToken(config, user.ID)
+		if err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, challenge
+	}
+	return s.startSession(config, user)
 }
 
 // Register creates a new user in the system.
-// @todo Replace shitty password hashing with a more robust mechanism (bcrypt)
+// Users are inactive until their email address is verified.
 func (s *Store) Register(config AuthConfig, email string, password string) (id int64, err error) {
 	if s.doesUserExistByEmail(email) {
-		return 0, errors.New("user already exists with given email")
+		return 0, ErrEmailTaken
+	}
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return 0, err
 	}
-	hashedPassword := hashPassword(config, password)
 	q := `
-		insert into users (email, password, uuid, created, modified)
-		values (?, ?, uuid(), now(), now())
+		insert into users (email, password, uuid, is_active, is_verified, created, modified)
+		values (?, ?, uuid(), 0, 0, now(), now())
 	`
 	res, err := s.DB.Exec(q, email, hashedPassword)
 	id, _ = res.LastInsertId()
 	return
 }
 
+func (s *Store) updatePassword(ID int64, password string) error {
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	q := "update users set password = ?, modified = now() where id = ?"
+	_, err = s.DB.Exec(q, hashedPassword, ID)
+	return err
+}
+
 func (s *Store) doesUserExistByEmail(email string) bool {
 	// flesh this out
 	q := "select count(*) from users where email = ?"
@@ -83,13 +113,22 @@ func (s *Store) doesUserExistByEmail(email string) bool {
 
 // ValidateAndDecodeAuthClaim will ensure the token provided was signed by us and decode its contents
 func ValidateAndDecodeAuthClaim(token string, config AuthConfig) (jwt.MapClaims, error) {
-	t, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		// Verify the algorhythm matches what we original signed
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(config.JWTSecret), nil
-	})
+	claims, err := parseToken(token, config)
+	if err != nil {
+		return claims, err
+	}
+	// Tokens issued before token types were introduced are access tokens.
+	if typ, ok := claims["typ"]; ok && typ != tokenTypeAccess {
+		return claims, fmt.Errorf("Unexpected token type: %v", typ)
+	}
+	return claims, nil
+}
+
+func parseToken(token string, config AuthConfig) (jwt.MapClaims, error) {
+	t, err := jwt.Parse(token, config.verificationKey)
+	if t == nil {
+		return jwt.MapClaims{}, err
+	}
 	return t.Claims.(jwt.MapClaims), err
 }
 
@@ -97,7 +136,7 @@ func ValidateAndDecodeAuthClaim(token string, config AuthConfig) (jwt.MapClaims,
 func (s *Store) ByID(ID int64) (User, error) {
 	user := User{}
 	q := `
-		select id, email, password, uuid, is_active, reset_password, created, modified
+		select id, email, password, uuid, is_active, is_verified, is_admin, reset_password, created, modified
 		from users where id = ?
 	`
 	err := s.DB.QueryRow(q, ID).Scan(
@@ -106,6 +145,8 @@ func (s *Store) ByID(ID int64) (User, error) {
 		&user.Password,
 		&user.UUID,
 		&user.IsActive,
+		&user.IsVerified,
+		&user.IsAdmin,
 		&user.ResetPassword,
 		&user.Created,
 		&user.Modified)
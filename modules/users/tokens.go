@@ -0,0 +1,216 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is unknown or expired.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again.
+	// The entire token family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+)
+
+const (
+	tokenTypeAccess      = "access"
+	tokenTypeVerifyEmail = "verify_email"
+)
+
+// TokenPair is the result of a successful authentication or refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// randomToken generates a URL safe random token of n bytes of entropy.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomID generates a random hex identifier suitable for jti and family claims.
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens creates a new access token and stores a new refresh token in the given family.
+func issueTokens(tx *sql.Tx, config AuthConfig, user User, family string) (TokenPair, error) {
+	var pair TokenPair
+	jti, err := randomID()
+	if err != nil {
+		return pair, err
+	}
+	now := time.Now()
+	pair.AccessToken, err = config.sign(jwt.MapClaims{
+		"typ":   tokenTypeAccess,
+		"id":    user.ID,
+		"uuid":  user.UUID,
+		"admin": user.IsAdmin,
+		"jti":   jti,
+		"sid":   family,
+		"nbf":   now.Unix(),
+		"exp":   now.Add(config.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return pair, err
+	}
+	pair.ExpiresIn = int64(config.AccessTokenTTL.Seconds())
+	pair.RefreshToken, err = randomToken(32)
+	if err != nil {
+		return pair, err
+	}
+	q := `
+		insert into refresh_tokens (user_id, family, token_hash, expires, created, modified)
+		values (?, ?, ?, ?, now(), now())
+	`
+	_, err = tx.Exec(q, user.ID, family, hashToken(pair.RefreshToken), now.Add(config.RefreshTokenTTL))
+	return pair, err
+}
+
+// startSession issues the first token pair of a new token family.
+func (s *Store) startSession(config AuthConfig, user User) (TokenPair, error) {
+	family, err := randomID()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	pair, err := issueTokens(tx, config, user, family)
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return pair, err
+}
+
+// Refresh exchanges a refresh token for a new token pair, rotating the refresh token.
+// Presenting a refresh token that was already rotated revokes the whole family.
+func (s *Store) Refresh(config AuthConfig, refreshToken string) (TokenPair, error) {
+	var pair TokenPair
+	var ID int64
+	var user User
+	var family string
+	var isUsed, isRevoked bool
+	var expires time.Time
+	q := `
+		select rt.id, rt.family, rt.is_used, rt.is_revoked, rt.expires, u.id, u.uuid, u.is_admin
+		from refresh_tokens rt
+		inner join users u on u.id = rt.user_id
+		where rt.token_hash = ?
+		for update
+	`
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return pair, err
+	}
+	err = tx.QueryRow(q, hashToken(refreshToken)).Scan(&ID, &family, &isUsed, &isRevoked, &expires, &user.ID, &user.UUID, &user.IsAdmin)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return pair, ErrInvalidRefreshToken
+		}
+		return pair, err
+	}
+	if isUsed || isRevoked {
+		err = revokeFamily(tx, family)
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		if err != nil {
+			return pair, err
+		}
+		return pair, ErrRefreshTokenReused
+	}
+	if time.Now().After(expires) {
+		tx.Rollback()
+		return pair, ErrInvalidRefreshToken
+	}
+	_, err = tx.Exec("update refresh_tokens set is_used = 1, modified = now() where id = ?", ID)
+	if err == nil {
+		pair, err = issueTokens(tx, config, user, family)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return pair, err
+}
+
+func revokeFamily(tx *sql.Tx, family string) error {
+	q := "update refresh_tokens set is_revoked = 1, modified = now() where family = ?"
+	_, err := tx.Exec(q, family)
+	return err
+}
+
+// RevokeUserSessions revokes every session of a user within the transaction.
+func RevokeUserSessions(tx *sql.Tx, userID int64) error {
+	q := "update refresh_tokens set is_revoked = 1, modified = now() where user_id = ?"
+	_, err := tx.Exec(q, userID)
+	return err
+}
+
+// Logout revokes the session of the provided access token claims along with the token itself.
+func (s *Store) Logout(claims jwt.MapClaims) error {
+	family, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if family != "" {
+		err = revokeFamily(tx, family)
+	}
+	if err == nil && jti != "" {
+		q := "insert ignore into revoked_tokens (jti, expires, created) values (?, ?, now())"
+		_, err = tx.Exec(q, jti, time.Unix(int64(exp), 0))
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
+
+// IsRevoked determines if the access token claims belong to a revoked token or session.
+func (s *Store) IsRevoked(claims jwt.MapClaims) (bool, error) {
+	family, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	q := `
+		select
+			(select count(*) from revoked_tokens where jti = ?) +
+			(select count(*) from refresh_tokens where family = ? and is_revoked = 1)
+	`
+	var count int
+	err := s.DB.QueryRow(q, jti, family).Scan(&count)
+	return count > 0, err
+}
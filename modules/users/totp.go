@@ -0,0 +1,304 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	totpDigits = 6
+	totpModulo = 1000000
+	totpPeriod = 30
+	// totpSkew is the number of periods before and after the current one that are accepted.
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	challengeTTL      = 5 * time.Minute
+
+	tokenTypeTwoFactorChallenge = "2fa_challenge"
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code does not match.
+	ErrInvalidTwoFactorCode = errors.New("invalid two factor code")
+	// ErrInvalidChallenge is returned when a two factor challenge token is invalid or expired.
+	ErrInvalidChallenge = errors.New("invalid or expired two factor challenge")
+	// ErrTwoFactorNotEnrolled is returned when confirming without a pending enrollment.
+	ErrTwoFactorNotEnrolled = errors.New("two factor authentication enrollment not started")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling while two factor is already enabled.
+	ErrTwoFactorAlreadyEnabled = errors.New("two factor authentication is already enabled")
+)
+
+// TwoFactorChallenge is returned by Login for users with two factor authentication enabled.
+// The challenge token is exchanged, along with a code, for a session via CompleteTwoFactorLogin.
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+func (c *TwoFactorChallenge) Error() string {
+	return "two factor authentication required"
+}
+
+// TwoFactorEnrollment contains what an authenticator app needs to be set up.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+// GenerateTOTPSecret creates a new random base32 encoded TOTP secret.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// TOTPURL builds the otpauth:// URL understood by authenticator apps.
+func TOTPURL(issuer string, account string, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("digits", fmt.Sprintf("%v", totpDigits))
+	params.Set("period", fmt.Sprintf("%v", totpPeriod))
+	return fmt.Sprintf("otpauth://totp/%v:%v?%v", url.PathEscape(issuer), url.PathEscape(account), params.Encode())
+}
+
+// GenerateTOTP calculates the RFC 6238 code of a base32 secret for the given time.
+func GenerateTOTP(secret string, t time.Time) (string, error) {
+	return totpCode(secret, t.Unix()/totpPeriod)
+}
+
+func totpCode(secret string, step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%totpModulo), nil
+}
+
+// validateTOTP checks a code within the allowed skew, returning the matched time step.
+// Steps at or before lastStep are rejected so a code can not be replayed.
+func validateTOTP(secret string, code string, t time.Time, lastStep int64) (int64, bool) {
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// EnrollTwoFactor generates a pending TOTP secret for the user.
+// Two factor authentication is not enforced until ConfirmTwoFactor succeeds.
+func (s *Store) EnrollTwoFactor(user User, issuer string) (TwoFactorEnrollment, error) {
+	var enrollment TwoFactorEnrollment
+	var enabled bool
+	if err := s.DB.QueryRow("select totp_enabled from users where id = ?", user.ID).Scan(&enabled); err != nil {
+		return enrollment, err
+	}
+	if enabled {
+		return enrollment, ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return enrollment, err
+	}
+	q := "update users set totp_secret = ?, totp_last_step = 0, modified = now() where id = ?"
+	if _, err = s.DB.Exec(q, secret, user.ID); err != nil {
+		return enrollment, err
+	}
+	enrollment.Secret = secret
+	enrollment.URL = TOTPURL(issuer, user.Email, secret)
+	return enrollment, nil
+}
+
+// PendingTwoFactorEnrollment retrieves the enrollment that has not yet been confirmed.
+func (s *Store) PendingTwoFactorEnrollment(user User, issuer string) (TwoFactorEnrollment, error) {
+	var enrollment TwoFactorEnrollment
+	var secret string
+	var enabled bool
+	q := "select totp_secret, totp_enabled from users where id = ?"
+	if err := s.DB.QueryRow(q, user.ID).Scan(&secret, &enabled); err != nil {
+		return enrollment, err
+	}
+	if enabled {
+		return enrollment, ErrTwoFactorAlreadyEnabled
+	}
+	if secret == "" {
+		return enrollment, ErrTwoFactorNotEnrolled
+	}
+	enrollment.Secret = secret
+	enrollment.URL = TOTPURL(issuer, user.Email, secret)
+	return enrollment, nil
+}
+
+// ConfirmTwoFactor enables two factor authentication once the user proves their authenticator works.
+// The returned recovery codes are single use and are only available now.
+func (s *Store) ConfirmTwoFactor(userID int64, code string) ([]string, error) {
+	var secret string
+	var enabled bool
+	var lastStep int64
+	q := "select totp_secret, totp_enabled, totp_last_step from users where id = ?"
+	if err := s.DB.QueryRow(q, userID).Scan(&secret, &enabled, &lastStep); err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if secret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	step, ok := validateTOTP(secret, code, time.Now(), lastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes := make([]string, recoveryCodeCount)
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec("delete from recovery_codes where user_id = ?", userID)
+	for i := range codes {
+		if err != nil {
+			break
+		}
+		codes[i], err = randomHex(5)
+		if err == nil {
+			q = "insert into recovery_codes (user_id, code_hash, created) values (?, ?, now())"
+			_, err = tx.Exec(q, userID, hashToken(codes[i]))
+		}
+	}
+	if err == nil {
+		q = "update users set totp_enabled = 1, totp_last_step = ?, modified = now() where id = ?"
+		_, err = tx.Exec(q, step, userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two factor authentication after re-authenticating the user.
+func (s *Store) DisableTwoFactor(config AuthConfig, userID int64, password string, code string) error {
+	var storedHash string
+	if err := s.DB.QueryRow("select password from users where id = ?", userID).Scan(&storedHash); err != nil {
+		return err
+	}
+	if _, err := verifyPassword(s.Hasher, config, storedHash, password); err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(userID, code); err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	q := "update users set totp_enabled = 0, totp_secret = '', totp_last_step = 0, modified = now() where id = ?"
+	_, err = tx.Exec(q, userID)
+	if err == nil {
+		_, err = tx.Exec("delete from recovery_codes where user_id = ?", userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		tx.Rollback()
+	}
+	return err
+}
+
+func challengeToken(config AuthConfig, userID int64) (*TwoFactorChallenge, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ": tokenTypeTwoFactorChallenge,
+		"id":  userID,
+		"exp": time.Now().Add(challengeTTL).Unix(),
+	})
+	signed, err := token.SignedString([]byte(config.JWTSecret))
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{ChallengeToken: signed, ExpiresIn: int64(challengeTTL.Seconds())}, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+func (s *Store) verifySecondFactor(userID int64, code string) error {
+	code = strings.Replace(strings.TrimSpace(code), " ", "", -1)
+	var secret string
+	var lastStep int64
+	q := "select totp_secret, totp_last_step from users where id = ? and totp_enabled = 1"
+	if err := s.DB.QueryRow(q, userID).Scan(&secret, &lastStep); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	if step, ok := validateTOTP(secret, code, time.Now(), lastStep); ok {
+		// Only succeed if no other request consumed this step in the meantime.
+		q = "update users set totp_last_step = ? where id = ? and totp_last_step < ?"
+		res, err := s.DB.Exec(q, step, userID, step)
+		if err != nil {
+			return err
+		}
+		if affected, _ := res.RowsAffected(); affected == 1 {
+			return nil
+		}
+		return ErrInvalidTwoFactorCode
+	}
+	q = "update recovery_codes set is_used = 1 where user_id = ? and code_hash = ? and is_used = 0"
+	res, err := s.DB.Exec(q, userID, hashToken(strings.ToLower(code)))
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 1 {
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or recovery code for a session.
+func (s *Store) CompleteTwoFactorLogin(config AuthConfig, challenge string, code string) (TokenPair, error) {
+	userID, err := ChallengeUserID(config, challenge)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err = s.verifySecondFactor(userID, code); err != nil {
+		return TokenPair{}, err
+	}
+	user, err := s.ByID(userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err = accountStatusError(user.IsActive, user.IsVerified); err != nil {
+		return TokenPair{}, err
+	}
+	return s.startSession(config, user)
+}
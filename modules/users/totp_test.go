@@ -0,0 +1,41 @@
+package users_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/users"
+)
+
+func TestGenerateTOTP(t *testing.T) {
+	// RFC 6238 appendix B SHA1 test vectors truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := users.GenerateTOTP(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if code != expected {
+			t.Errorf("Expected %v at %v but got %v", expected, unix, code)
+		}
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	url := users.TOTPURL("Boxmeup", "test@test.com", "ABCDEF")
+	if !strings.HasPrefix(url, "otpauth://totp/Boxmeup:test@test.com?") {
+		t.Errorf("Unexpected otpauth url %v", url)
+	}
+	if !strings.Contains(url, "secret=ABCDEF") {
+		t.Errorf("Expected secret in otpauth url %v", url)
+	}
+}
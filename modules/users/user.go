@@ -9,6 +9,8 @@ type User struct {
 	Password      string    `json:"-"`
 	UUID          string    `json:"uuid"`
 	IsActive      bool      `json:"is_active"`
+	IsVerified    bool      `json:"is_verified"`
+	IsAdmin       bool      `json:"is_admin"`
 	ResetPassword bool      `json:"-"`
 	Created       time.Time `json:"created"`
 	Modified      time.Time `json:"modified"`
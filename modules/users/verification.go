@@ -0,0 +1,104 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrAccountNotVerified is returned when the user has not verified their email address.
+	ErrAccountNotVerified = errors.New("account email address has not been verified")
+	// ErrAccountDeactivated is returned when a verified account has been deactivated.
+	ErrAccountDeactivated = errors.New("account has been deactivated")
+	// ErrInvalidVerificationToken is returned when a verification token is invalid, expired or stale.
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+)
+
+func accountStatusError(isActive bool, isVerified bool) error {
+	if !isVerified {
+		return ErrAccountNotVerified
+	}
+	if !isActive {
+		return ErrAccountDeactivated
+	}
+	return nil
+}
+
+// AccountStatus reports if the user may use the API.
+// Returns ErrAccountNotVerified or ErrAccountDeactivated for accounts that may not.
+func (s *Store) AccountStatus(ID int64) error {
+	var isActive, isVerified bool
+	err := s.DB.QueryRow("select is_active, is_verified from users where id = ?", ID).Scan(&isActive, &isVerified)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+	return accountStatusError(isActive, isVerified)
+}
+
+// ByEmail retrieves a user by email address.
+func (s *Store) ByEmail(email string) (User, error) {
+	var ID int64
+	err := s.DB.QueryRow("select id from users where email = ?", email).Scan(&ID)
+	if err == sql.ErrNoRows {
+		return User{}, ErrUserNotFound
+	} else if err != nil {
+		return User{}, err
+	}
+	return s.ByID(ID)
+}
+
+// VerificationToken produces a signed token that verifies ownership of the user's email address.
+func VerificationToken(config AuthConfig, user User, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"typ":   tokenTypeVerifyEmail,
+		"id":    user.ID,
+		"email": user.Email,
+		"exp":   time.Now().Add(ttl).Unix(),
+	})
+	return token.SignedString([]byte(config.JWTSecret))
+}
+
+// Verify activates the account identified by a verification token.
+// The token is only valid for the email address it was issued for.
+// Deactivated accounts are not reactivated by verifying again.
+func (s *Store) Verify(config AuthConfig, token string) error {
+	claims, err := parseToken(token, config)
+	if err != nil || claims["typ"] != tokenTypeVerifyEmail {
+		return ErrInvalidVerificationToken
+	}
+	ID, _ := claims["id"].(float64)
+	email, _ := claims["email"].(string)
+	q := `
+		update users set is_active = 1, is_verified = 1, modified = now()
+		where id = ? and email = ? and is_verified = 0
+	`
+	res, err := s.DB.Exec(q, int64(ID), email)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		return nil
+	}
+	var count int
+	q = "select count(*) from users where id = ? and email = ? and is_verified = 1"
+	if err = s.DB.QueryRow(q, int64(ID), email).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// MarkVerified activates an account whose email address was proven by other means,
+// such as following a link that was sent to it.
+func (s *Store) MarkVerified(ID int64) error {
+	q := "update users set is_active = 1, is_verified = 1, modified = now() where id = ? and is_verified = 0"
+	_, err := s.DB.Exec(q, ID)
+	return err
+}
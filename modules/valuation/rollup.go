@@ -0,0 +1,40 @@
+package valuation
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Rollup totals the value of items, aliased ci, grouped by a column such as the container of the items.
+// The source is the from and where clauses selecting the items, with args for its placeholders.
+// Every one of the IDs has an entry in the result, even when none of its items are valued.
+func Rollup(db *sql.DB, groupColumn string, source string, IDs []int64, args ...interface{}) (map[int64]Totals, error) {
+	byID := make(map[int64]Totals, len(IDs))
+	for _, ID := range IDs {
+		byID[ID] = Totals{}
+	}
+	if len(IDs) == 0 {
+		return byID, nil
+	}
+	q := fmt.Sprintf(`
+		select %v, ci.currency,
+			sum(ci.quantity * coalesce(ci.purchase_price, 0)),
+			sum(ci.quantity * coalesce(ci.replacement_value, 0))
+		from %v and ci.currency != ''
+		group by %v, ci.currency
+	`, groupColumn, source, groupColumn)
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return byID, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ID int64
+		var total Total
+		if err = rows.Scan(&ID, &total.Currency, &total.PurchasePrice, &total.ReplacementValue); err != nil {
+			return byID, err
+		}
+		byID[ID] = byID[ID].Add(total.Currency, total.PurchasePrice, total.ReplacementValue)
+	}
+	return byID, rows.Err()
+}
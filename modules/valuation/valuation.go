@@ -0,0 +1,149 @@
+package valuation
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxAmount is the largest amount accepted, 999,999,999.99.
+	MaxAmount Amount = 99999999999
+	// DateFormat is the format of purchase dates.
+	DateFormat = "2006-01-02"
+)
+
+var (
+	// ErrInvalidAmount is returned for amounts that are negative, too large or have more than two decimals.
+	ErrInvalidAmount = errors.New("amounts must be positive numbers with at most two decimals")
+	// ErrInvalidCurrency is returned for currencies that are not a three letter ISO 4217 code.
+	ErrInvalidCurrency = errors.New("currency must be a three letter ISO 4217 code such as USD")
+	// ErrInvalidDate is returned for dates that are not formatted as DateFormat.
+	ErrInvalidDate = errors.New("dates must be formatted as YYYY-MM-DD")
+)
+
+var (
+	amountPattern   = regexp.MustCompile(`^(\d+)(?:\.(\d{1,2}))?$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Amount is a sum of money in hundredths of its currency unit, e.g. cents.
+type Amount int64
+
+// ParseAmount reads a decimal amount such as 12, 12.5 or 12.50.
+func ParseAmount(value string) (Amount, error) {
+	matches := amountPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil || len(matches[1]) > 9 {
+		return 0, ErrInvalidAmount
+	}
+	units, _ := strconv.ParseInt(matches[1], 10, 64)
+	cents, _ := strconv.ParseInt((matches[2] + "00")[:2], 10, 64)
+	amount := Amount(units*100 + cents)
+	if amount > MaxAmount {
+		return 0, ErrInvalidAmount
+	}
+	return amount, nil
+}
+
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign, a = "-", -a
+	}
+	return fmt.Sprintf("%v%d.%02d", sign, a/100, a%100)
+}
+
+// MarshalJSON outputs the amount as a JSON number with two decimals.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// Times is the amount for a quantity of units.
+func (a Amount) Times(quantity int) Amount {
+	return a * Amount(quantity)
+}
+
+// NormalizeCurrency validates a currency code, returning it in upper case.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currencyPattern.MatchString(code) {
+		return "", ErrInvalidCurrency
+	}
+	return code, nil
+}
+
+// Date is a calendar date without a time of day.
+type Date struct {
+	time.Time
+}
+
+// ParseDate reads a date formatted as DateFormat.
+func ParseDate(value string) (Date, error) {
+	date, err := time.Parse(DateFormat, strings.TrimSpace(value))
+	if err != nil {
+		return Date{}, ErrInvalidDate
+	}
+	return Date{date}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(DateFormat)
+}
+
+// MarshalJSON outputs the date formatted as DateFormat.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// Scan reads a date column, with or without parseTime enabled on the connection.
+func (d *Date) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case time.Time:
+		d.Time = time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+		return nil
+	case []byte:
+		date, err := ParseDate(string(value))
+		*d = date
+		return err
+	case string:
+		date, err := ParseDate(value)
+		*d = date
+		return err
+	}
+	return fmt.Errorf("can not scan %T into a date", src)
+}
+
+// Value stores the date formatted as DateFormat.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Total is the value of a group of items priced in the same currency.
+// Prices are per unit, so each item counts as many times as its quantity.
+type Total struct {
+	Currency         string `json:"currency"`
+	PurchasePrice    Amount `json:"purchase_price"`
+	ReplacementValue Amount `json:"replacement_value"`
+}
+
+// Totals holds a Total for each currency, ordered by currency.
+type Totals []Total
+
+// Add includes an amount of a currency in the totals.
+func (t Totals) Add(currency string, purchasePrice Amount, replacementValue Amount) Totals {
+	for i := range t {
+		if t[i].Currency == currency {
+			t[i].PurchasePrice += purchasePrice
+			t[i].ReplacementValue += replacementValue
+			return t
+		}
+	}
+	t = append(t, Total{Currency: currency, PurchasePrice: purchasePrice, ReplacementValue: replacementValue})
+	sort.Slice(t, func(i, j int) bool { return t[i].Currency < t[j].Currency })
+	return t
+}
@@ -0,0 +1,82 @@
+package valuation_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cjsaylor/boxmeup-go/modules/valuation"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]valuation.Amount{
+		"12":           1200,
+		"12.5":         1250,
+		"12.05":        1205,
+		" 0.99 ":       99,
+		"999999999.99": valuation.MaxAmount,
+	}
+	for value, expected := range cases {
+		if amount, err := valuation.ParseAmount(value); err != nil || amount != expected {
+			t.Errorf("%q: expected %v but got %v, %v", value, expected, amount, err)
+		}
+	}
+	for _, value := range []string{"", "-1", "1.234", "1,50", "1e3", "1000000000"} {
+		if _, err := valuation.ParseAmount(value); err != valuation.ErrInvalidAmount {
+			t.Errorf("%q: expected ErrInvalidAmount but got %v", value, err)
+		}
+	}
+}
+
+func TestAmount_MarshalJSON(t *testing.T) {
+	encoded, _ := json.Marshal(map[string]valuation.Amount{"a": 1205, "b": 7, "c": 0})
+	if expected := `{"a":12.05,"b":0.07,"c":0.00}`; string(encoded) != expected {
+		t.Errorf("Expected %v but got %v", expected, string(encoded))
+	}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	if currency, err := valuation.NormalizeCurrency(" eur "); err != nil || currency != "EUR" {
+		t.Errorf("Expected EUR but got %v, %v", currency, err)
+	}
+	for _, code := range []string{"", "EURO", "E1R", "$"} {
+		if _, err := valuation.NormalizeCurrency(code); err != valuation.ErrInvalidCurrency {
+			t.Errorf("%q: expected ErrInvalidCurrency but got %v", code, err)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	date, err := valuation.ParseDate("2017-05-15")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if encoded, _ := json.Marshal(date); string(encoded) != `"2017-05-15"` {
+		t.Errorf("Unexpected JSON %v", string(encoded))
+	}
+	var scanned valuation.Date
+	if err = scanned.Scan(time.Date(2017, 5, 15, 0, 0, 0, 0, time.Local)); err != nil || scanned.String() != "2017-05-15" {
+		t.Errorf("Expected 2017-05-15 but got %v, %v", scanned, err)
+	}
+	if err = scanned.Scan([]byte("2016-01-31")); err != nil || scanned.String() != "2016-01-31" {
+		t.Errorf("Expected 2016-01-31 but got %v, %v", scanned, err)
+	}
+	if _, err = valuation.ParseDate("15/05/2017"); err != valuation.ErrInvalidDate {
+		t.Errorf("Expected ErrInvalidDate but got %v", err)
+	}
+}
+
+func TestTotals_Add(t *testing.T) {
+	var totals valuation.Totals
+	totals = totals.Add("USD", 1000, 1500)
+	totals = totals.Add("EUR", 500, 0)
+	totals = totals.Add("USD", 250, 250)
+	expected := valuation.Totals{
+		{Currency: "EUR", PurchasePrice: 500, ReplacementValue: 0},
+		{Currency: "USD", PurchasePrice: 1250, ReplacementValue: 1750},
+	}
+	if len(totals) != len(expected) || totals[0] != expected[0] || totals[1] != expected[1] {
+		t.Errorf("Expected %v but got %v", expected, totals)
+	}
+}
@@ -20,6 +20,40 @@
 /*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
 
 
+# Dump of table admin_audit_log
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `admin_audit_log`;
+
+CREATE TABLE `admin_audit_log` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `admin_user_id` int(11) NOT NULL,
+  `action` varchar(40) NOT NULL,
+  `target_user_id` int(11) NOT NULL DEFAULT '0',
+  `details` varchar(255) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `admin_user_id` (`admin_user_id`),
+  KEY `target_user_id` (`target_user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Audit trail of administrative actions';
+
+
+
+# Dump of table api_nonces
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `api_nonces`;
+
+CREATE TABLE `api_nonces` (
+  `api_user_id` int(11) NOT NULL,
+  `nonce` varchar(64) NOT NULL,
+  `expires` datetime NOT NULL,
+  PRIMARY KEY (`api_user_id`,`nonce`),
+  KEY `expires` (`expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Recently used API request nonces for replay protection';
+
+
+
 # Dump of table api_users
 # ------------------------------------------------------------
 
@@ -28,16 +62,18 @@ DROP TABLE IF EXISTS `api_users`;
 CREATE TABLE `api_users` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `user_id` int(11) NOT NULL,
+  `name` varchar(60) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
   `api_key` varchar(40) COLLATE utf8_unicode_ci NOT NULL,
   `secret_key` varchar(40) COLLATE utf8_unicode_ci DEFAULT '',
   `is_active` tinyint(1) NOT NULL DEFAULT '1',
+  `last_used` datetime DEFAULT NULL,
   `created` datetime NOT NULL,
   `modified` datetime NOT NULL,
   PRIMARY KEY (`id`),
   KEY `user_id` (`user_id`),
-  KEY `api_key` (`api_key`),
+  UNIQUE KEY `api_key` (`api_key`),
   KEY `secret_key` (`secret_key`),
-  CONSTRAINT `fk_user_id_constraint` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION
+  CONSTRAINT `fk_user_id_constraint` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
 
 
@@ -53,17 +89,44 @@ CREATE TABLE `container_items` (
   `uuid` varchar(36) DEFAULT NULL,
   `body` varchar(100) DEFAULT NULL,
   `quantity` int(11) NOT NULL DEFAULT '1',
+  `barcode` varchar(255) NOT NULL DEFAULT '',
+  `barcode_type` varchar(10) NOT NULL DEFAULT '' COMMENT 'upc-a, ean-13, code128 or qr',
+  `purchase_price` bigint(20) DEFAULT NULL COMMENT 'Per unit, in hundredths of the currency',
+  `replacement_value` bigint(20) DEFAULT NULL COMMENT 'Per unit, in hundredths of the currency',
+  `currency` char(3) NOT NULL DEFAULT '' COMMENT 'ISO 4217 code, set when the item is valued',
+  `purchase_date` date DEFAULT NULL,
   `created` datetime DEFAULT NULL,
   `modified` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL COMMENT 'Set while in the trash',
+  `deleted_by` int(11) DEFAULT NULL COMMENT 'User whose trash holds the row',
   PRIMARY KEY (`id`),
   KEY `container` (`container_id`),
+  KEY `barcode` (`barcode`),
   KEY `fk_container_items_containers1` (`container_id`),
   KEY `uuid` (`uuid`),
-  CONSTRAINT `fk_container_items_containers1` FOREIGN KEY (`container_id`) REFERENCES `containers` (`id`) ON DELETE NO ACTION ON UPDATE NO ACTION
+  KEY `deleted_by` (`deleted_by`,`deleted_at`),
+  CONSTRAINT `fk_container_items_containers1` FOREIGN KEY (`container_id`) REFERENCES `containers` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Items kept in containers';
 
 
 
+# Dump of table container_tags
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `container_tags`;
+
+CREATE TABLE `container_tags` (
+  `container_id` int(11) NOT NULL,
+  `tag_id` int(11) NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`container_id`,`tag_id`),
+  KEY `tag_id` (`tag_id`),
+  CONSTRAINT `fk_container_tags_containers` FOREIGN KEY (`container_id`) REFERENCES `containers` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION,
+  CONSTRAINT `fk_container_tags_tags` FOREIGN KEY (`tag_id`) REFERENCES `tags` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+
+
 # Dump of table containers
 # ------------------------------------------------------------
 
@@ -72,22 +135,131 @@ DROP TABLE IF EXISTS `containers`;
 CREATE TABLE `containers` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `user_id` int(11) NOT NULL DEFAULT '0',
+  `household_id` int(11) NOT NULL DEFAULT '0',
+  `parent_id` int(11) NOT NULL DEFAULT '0',
   `location_id` int(11) DEFAULT '0',
   `uuid` varchar(36) DEFAULT NULL,
+  `code_number` int(11) unsigned DEFAULT NULL COMMENT 'Sequence of the short code within the household, e.g. BOX-0042',
   `name` varchar(36) DEFAULT NULL,
   `slug` varchar(40) DEFAULT NULL,
   `container_item_count` int(10) unsigned DEFAULT '0',
   `created` datetime DEFAULT NULL,
   `modified` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL COMMENT 'Set while in the trash',
+  `deleted_by` int(11) DEFAULT NULL COMMENT 'User whose trash holds the row',
   PRIMARY KEY (`id`),
   KEY `user` (`user_id`),
   KEY `fk_containers_users` (`user_id`),
   KEY `location_id` (`location_id`),
-  CONSTRAINT `fk_containers_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE NO ACTION ON UPDATE NO ACTION
+  KEY `household_id` (`household_id`),
+  KEY `parent_id` (`parent_id`),
+  KEY `uuid` (`uuid`),
+  UNIQUE KEY `household_code` (`household_id`,`code_number`),
+  KEY `deleted_by` (`deleted_by`,`deleted_at`),
+  CONSTRAINT `fk_containers_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Container objects reference head for container items';
 
 
 
+# Dump of table fields
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `fields`;
+
+CREATE TABLE `fields` (
+  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
+  `household_id` int(11) NOT NULL,
+  `name` varchar(40) NOT NULL,
+  `type` varchar(10) NOT NULL COMMENT 'text, number, date, boolean, enum or url',
+  `options` text COMMENT 'JSON list of the choices of an enum field',
+  `created` datetime DEFAULT NULL,
+  `modified` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `household_name` (`household_id`,`name`),
+  CONSTRAINT `fk_fields_households` FOREIGN KEY (`household_id`) REFERENCES `households` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Custom item fields defined by households';
+
+
+
+# Dump of table household_invites
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `household_invites`;
+
+CREATE TABLE `household_invites` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `household_id` int(11) NOT NULL,
+  `invited_by` int(11) NOT NULL,
+  `email` varchar(60) NOT NULL DEFAULT '',
+  `role` varchar(10) NOT NULL DEFAULT 'viewer',
+  `status` varchar(10) NOT NULL DEFAULT 'pending',
+  `expires` datetime NOT NULL,
+  `answered` datetime DEFAULT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `household_id` (`household_id`,`status`),
+  KEY `email` (`email`),
+  CONSTRAINT `fk_household_invites_households` FOREIGN KEY (`household_id`) REFERENCES `households` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Pending and answered invitations to join a household';
+
+
+
+# Dump of table household_members
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `household_members`;
+
+CREATE TABLE `household_members` (
+  `household_id` int(11) NOT NULL,
+  `user_id` int(11) NOT NULL,
+  `role` varchar(10) NOT NULL DEFAULT 'viewer',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`household_id`,`user_id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `fk_household_members_households` FOREIGN KEY (`household_id`) REFERENCES `households` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION,
+  CONSTRAINT `fk_household_members_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Users with access to a household and their role (owner, editor, viewer)';
+
+
+
+# Dump of table households
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `households`;
+
+CREATE TABLE `households` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `uuid` varchar(36) NOT NULL DEFAULT '',
+  `name` varchar(60) NOT NULL DEFAULT '',
+  `personal_user_id` int(11) DEFAULT NULL,
+  `container_code_seq` int(11) unsigned NOT NULL DEFAULT '0' COMMENT 'Last short code handed out to a container',
+  `created` datetime NOT NULL,
+  `modified` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `personal_user_id` (`personal_user_id`),
+  KEY `uuid` (`uuid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Shared inventories of locations and containers';
+
+
+
+# Dump of table item_field_values
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `item_field_values`;
+
+CREATE TABLE `item_field_values` (
+  `item_id` int(11) NOT NULL,
+  `field_id` int(11) unsigned NOT NULL,
+  `value` varchar(255) NOT NULL,
+  `number_value` double DEFAULT NULL COMMENT 'Value of number fields, for sorting',
+  PRIMARY KEY (`item_id`,`field_id`),
+  KEY `field_value` (`field_id`,`value`),
+  CONSTRAINT `fk_item_field_values_items` FOREIGN KEY (`item_id`) REFERENCES `container_items` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION,
+  CONSTRAINT `fk_item_field_values_fields` FOREIGN KEY (`field_id`) REFERENCES `fields` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Values of custom fields on items';
+
+
+
 # Dump of table locations
 # ------------------------------------------------------------
 
@@ -96,6 +268,9 @@ DROP TABLE IF EXISTS `locations`;
 CREATE TABLE `locations` (
   `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
   `user_id` int(11) NOT NULL,
+  `household_id` int(11) NOT NULL DEFAULT '0',
+  `parent_id` int(11) unsigned NOT NULL DEFAULT '0',
+  `path` varchar(255) NOT NULL DEFAULT '' COMMENT 'IDs of ancestor locations, outermost first: 1/4/',
   `uuid` char(36) DEFAULT NULL,
   `name` varchar(40) DEFAULT NULL,
   `is_mappable` tinyint(1) NOT NULL DEFAULT '0',
@@ -103,13 +278,195 @@ CREATE TABLE `locations` (
   `container_count` int(11) NOT NULL DEFAULT '0',
   `created` datetime DEFAULT NULL,
   `modified` datetime DEFAULT NULL,
+  `deleted_at` datetime DEFAULT NULL COMMENT 'Set while in the trash',
+  `deleted_by` int(11) DEFAULT NULL COMMENT 'User whose trash holds the row',
   PRIMARY KEY (`id`),
   KEY `user_id` (`user_id`),
-  KEY `uuid` (`uuid`)
+  KEY `household_id` (`household_id`),
+  KEY `uuid` (`uuid`),
+  KEY `parent_id` (`parent_id`),
+  KEY `path` (`path`),
+  KEY `deleted_by` (`deleted_by`,`deleted_at`),
+  CONSTRAINT `fk_locations_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
 
 
+# Dump of table login_attempts
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `login_attempts`;
+
+CREATE TABLE `login_attempts` (
+  `throttle_key` varchar(191) NOT NULL,
+  `failures` int(11) NOT NULL DEFAULT '0',
+  `locked_until` datetime DEFAULT NULL,
+  `expires` datetime NOT NULL,
+  PRIMARY KEY (`throttle_key`),
+  KEY `expires` (`expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Failed login counters per account and IP address';
+
+
+
+# Dump of table password_resets
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `password_resets`;
+
+CREATE TABLE `password_resets` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `is_used` tinyint(1) NOT NULL DEFAULT '0',
+  `expires` datetime NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `fk_password_resets_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Single use password reset tokens';
+
+
+
+# Dump of table photos
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `photos`;
+
+CREATE TABLE `photos` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `uuid` char(36) NOT NULL,
+  `owner_type` varchar(10) NOT NULL,
+  `owner_id` int(11) NOT NULL,
+  `content_type` varchar(20) NOT NULL,
+  `size` int(11) unsigned NOT NULL DEFAULT '0',
+  `width` int(11) unsigned NOT NULL DEFAULT '0',
+  `height` int(11) unsigned NOT NULL DEFAULT '0',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uuid` (`uuid`),
+  KEY `owner` (`owner_type`,`owner_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Photos of containers and items, images are kept in the blob store';
+
+
+
+# Dump of table receipts
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `receipts`;
+
+CREATE TABLE `receipts` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `uuid` char(36) NOT NULL,
+  `item_id` int(11) NOT NULL,
+  `content_type` varchar(20) NOT NULL,
+  `size` int(11) unsigned NOT NULL DEFAULT '0',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uuid` (`uuid`),
+  UNIQUE KEY `item_id` (`item_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Proofs of purchase of items, files are kept in the blob store';
+
+
+
+# Dump of table recovery_codes
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `recovery_codes`;
+
+CREATE TABLE `recovery_codes` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `is_used` tinyint(1) NOT NULL DEFAULT '0',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `fk_recovery_codes_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Single use two factor recovery codes';
+
+
+
+# Dump of table refresh_tokens
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `refresh_tokens`;
+
+CREATE TABLE `refresh_tokens` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `family` varchar(36) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `is_used` tinyint(1) NOT NULL DEFAULT '0',
+  `is_revoked` tinyint(1) NOT NULL DEFAULT '0',
+  `expires` datetime NOT NULL,
+  `created` datetime NOT NULL,
+  `modified` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `family` (`family`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `fk_refresh_tokens_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Rotating refresh tokens grouped by session family';
+
+
+
+# Dump of table revoked_tokens
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `revoked_tokens`;
+
+CREATE TABLE `revoked_tokens` (
+  `jti` varchar(36) NOT NULL,
+  `expires` datetime NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`jti`),
+  KEY `expires` (`expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Access tokens revoked before their expiration';
+
+
+
+# Dump of table security_events
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `security_events`;
+
+CREATE TABLE `security_events` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `event` varchar(40) NOT NULL,
+  `ip_address` varchar(45) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `fk_security_events_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Failed logins and lockouts visible to the account owner';
+
+
+
+# Dump of table share_links
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `share_links`;
+
+CREATE TABLE `share_links` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `token_hash` char(64) NOT NULL,
+  `household_id` int(11) NOT NULL,
+  `created_by` int(11) NOT NULL,
+  `target_type` varchar(10) NOT NULL,
+  `target_id` int(11) NOT NULL,
+  `expires` datetime DEFAULT NULL,
+  `is_revoked` tinyint(1) NOT NULL DEFAULT '0',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `target` (`target_type`,`target_id`),
+  CONSTRAINT `fk_share_links_households` FOREIGN KEY (`household_id`) REFERENCES `households` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Revocable public read-only links to containers and locations';
+
+
+
 # Dump of table sphinx_counters
 # ------------------------------------------------------------
 
@@ -123,6 +480,24 @@ CREATE TABLE `sphinx_counters` (
 
 
 
+# Dump of table tags
+# ------------------------------------------------------------
+
+DROP TABLE IF EXISTS `tags`;
+
+CREATE TABLE `tags` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `household_id` int(11) NOT NULL,
+  `name` varchar(40) NOT NULL,
+  `created` datetime NOT NULL,
+  `modified` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `household_name` (`household_id`,`name`),
+  CONSTRAINT `fk_tags_households` FOREIGN KEY (`household_id`) REFERENCES `households` (`id`) ON DELETE CASCADE ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='Tag vocabulary of a household';
+
+
+
 # Dump of table users
 # ------------------------------------------------------------
 
@@ -131,11 +506,15 @@ DROP TABLE IF EXISTS `users`;
 CREATE TABLE `users` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `email` varchar(60) CHARACTER SET utf8 NOT NULL DEFAULT '',
-  `password` varchar(60) CHARACTER SET utf8 NOT NULL DEFAULT '',
+  `password` varchar(255) CHARACTER SET utf8 NOT NULL DEFAULT '',
   `uuid` varchar(36) CHARACTER SET utf8 NOT NULL DEFAULT '',
   `is_active` tinyint(1) NOT NULL DEFAULT '1',
+  `is_verified` tinyint(1) NOT NULL DEFAULT '1',
   `is_admin` tinyint(1) NOT NULL DEFAULT '0',
   `reset_password` tinyint(1) NOT NULL DEFAULT '0',
+  `totp_secret` varchar(64) CHARACTER SET utf8 NOT NULL DEFAULT '',
+  `totp_enabled` tinyint(1) NOT NULL DEFAULT '0',
+  `totp_last_step` bigint(20) NOT NULL DEFAULT '0',
   `created` datetime NOT NULL,
   `modified` datetime NOT NULL,
   PRIMARY KEY (`id`),
@@ -147,6 +526,55 @@ CREATE TABLE `users` (
 
 
 
+
+# Upgrade existing data
+# ------------------------------------------------------------
+# Safe to run on its own against an existing database; every statement only touches rows not yet upgraded.
+
+# Give every user a personal household and adopt the locations and containers created before households existed.
+INSERT INTO `households` (`uuid`, `name`, `personal_user_id`, `created`, `modified`)
+  SELECT uuid(), 'Personal', u.id, now(), now()
+  FROM `users` u
+  LEFT JOIN `households` h ON h.personal_user_id = u.id
+  WHERE h.id IS NULL;
+
+INSERT INTO `household_members` (`household_id`, `user_id`, `role`, `created`)
+  SELECT h.id, h.personal_user_id, 'owner', now()
+  FROM `households` h
+  LEFT JOIN `household_members` m ON m.household_id = h.id AND m.user_id = h.personal_user_id
+  WHERE h.personal_user_id IS NOT NULL AND m.household_id IS NULL;
+
+UPDATE `locations` l
+  INNER JOIN `households` h ON h.personal_user_id = l.user_id
+  SET l.household_id = h.id
+  WHERE l.household_id = 0;
+
+UPDATE `containers` c
+  INNER JOIN `households` h ON h.personal_user_id = c.user_id
+  SET c.household_id = h.id
+  WHERE c.household_id = 0;
+
+
+# Give containers created before short codes existed the next codes of their household, in the order they were created.
+UPDATE `containers` c
+  INNER JOIN (
+    SELECT a.id, count(*) AS position
+    FROM `containers` a
+    INNER JOIN `containers` b ON b.household_id = a.household_id AND b.code_number IS NULL AND b.id <= a.id
+    WHERE a.code_number IS NULL AND a.household_id > 0
+    GROUP BY a.id
+  ) uncoded ON uncoded.id = c.id
+  INNER JOIN `households` h ON h.id = c.household_id
+  SET c.code_number = h.container_code_seq + uncoded.position;
+
+UPDATE `households` h
+  INNER JOIN (
+    SELECT household_id, max(code_number) AS code_number FROM `containers` GROUP BY household_id
+  ) c ON c.household_id = h.id
+  SET h.container_code_seq = c.code_number
+  WHERE c.code_number > h.container_code_seq;
+
+
 /*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
 /*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
 /*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;